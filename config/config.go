@@ -2,20 +2,128 @@ package config
 
 import (
 	"log"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	DBHost         string
-	DBPort         string
-	DBUser         string
-	DBPassword     string
-	DBName         string
-	JWTSecret      string
-	JWTExpiryHours int
+	DBHost     string
+	DBPort     string
+	DBUser     string
+	DBPassword string
+	DBName     string
+	JWTSecret  string
+
+	// JWTExpiryMinutes is the lifetime of access tokens. Keep it short:
+	// clients renew them with the refresh token.
+	JWTExpiryMinutes        int
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles []string
+	JWTAcceptHS256Until     time.Time
+	JWTIssuer               string
+	JWTAudience             string
+
+	RefreshTokenExpiryHours int
+	RevocationSyncSeconds   int
+
+	ServiceClients map[string]string
+
+	MFAIssuer             string
+	MFATokenExpiryMinutes int
+
+	MailerDriver string
+	MailFrom     string
+	MailFileDir  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	EmailVerificationURL         string
+	EmailVerificationExpiryHours int
+	RequireEmailVerification     bool
+
+	EmailResendCooldownSeconds int
+	EmailMaxSendsPerHour       int
+
+	SMSDriver             string
+	PhoneOTPExpiryMinutes int
+	PhoneOTPMaxAttempts   int
+
+	PasswordResetExpiryMinutes   int
+	PasswordResetMaxAttempts     int
+	PasswordResetCooldownSeconds int
+	PasswordResetMaxSendsPerHour int
+
+	MagicLinkURL           string
+	MagicLinkExpiryMinutes int
+
+	PhoneOTPLoginAutoRegister     bool
+	PhoneOTPResendCooldownSeconds int
+	PhoneOTPMaxSendsPerHour       int
+
+	LockoutThreshold   int
+	LockoutBaseSeconds int
+	LockoutMaxSeconds  int
+
+	HardenedAuthErrors bool
+
+	RateLimitDriver        string
+	RateLimitsByIP         map[string]string
+	RateLimitsByIdentifier map[string]string
+
+	// TrustedProxies may set X-Forwarded-For; with none, the client IP is
+	// always the connection's peer address.
+	TrustedProxies []string
+
+	PasswordHashAlgorithm string
+	Argon2Memory          int
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	BcryptCost            int
+
+	PasswordMinLength      int
+	PasswordMaxLength      int
+	PasswordMinCharClasses int
+	PasswordMinEntropyBits int
+	BreachedPasswordsFile  string
+
+	WebAuthnRPID           string
+	WebAuthnRPName         string
+	WebAuthnOrigins        []string
+	WebAuthnTimeoutSeconds int
+
+	OIDCProviders          map[string]OIDCProvider
+	OIDCRedirectURL        string
+	OIDCStateExpiryMinutes int
+
+	APIKeyMaxPerUser int
+
+	// DefaultRoles are held by every account without being stored.
+	DefaultRoles []string
+
+	ImpersonationExpiryMinutes int
+}
+
+// OIDCProvider is an external identity provider users may sign in with,
+// identified by its issuer URL.
+type OIDCProvider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
 }
 
+// Default rate limits per route group, as "<burst>/<period>". Groups missing
+// from RATE_LIMITS_BY_IP or RATE_LIMITS_BY_IDENTIFIER fall back to these.
+// Refresh requests carry no identifier, and with short-lived access tokens
+// every client sends them regularly, so they get a roomier IP limit only.
+var (
+	defaultRateLimitsByIP         = map[string]string{"login": "20/1m", "register": "10/1h", "code": "10/1h", "refresh": "60/1m"}
+	defaultRateLimitsByIdentifier = map[string]string{"login": "5/1m", "register": "3/1h", "code": "3/10m"}
+)
+
 func LoadConfig() Config {
 	viper.SetConfigFile(".env")
 	if err := viper.ReadInConfig(); err != nil {
@@ -25,18 +133,339 @@ func LoadConfig() Config {
 	viper.AutomaticEnv()
 
 	config := Config{
-		DBHost:         viper.GetString("DB_HOST"),
-		DBPort:         viper.GetString("DB_PORT"),
-		DBUser:         viper.GetString("DB_USER"),
-		DBPassword:     viper.GetString("DB_PASSWORD"),
-		DBName:         viper.GetString("DB_NAME"),
-		JWTSecret:      viper.GetString("JWT_SECRET"),
-		JWTExpiryHours: viper.GetInt("JWT_EXPIRY_HOURS"),
+		DBHost:     viper.GetString("DB_HOST"),
+		DBPort:     viper.GetString("DB_PORT"),
+		DBUser:     viper.GetString("DB_USER"),
+		DBPassword: viper.GetString("DB_PASSWORD"),
+		DBName:     viper.GetString("DB_NAME"),
+		JWTSecret:  viper.GetString("JWT_SECRET"),
+
+		JWTExpiryMinutes:        viper.GetInt("JWT_EXPIRY_MINUTES"),
+		JWTSigningKeyFile:       viper.GetString("JWT_SIGNING_KEY_FILE"),
+		JWTVerificationKeyFiles: splitList(viper.GetString("JWT_VERIFICATION_KEY_FILES")),
+		JWTIssuer:               viper.GetString("JWT_ISSUER"),
+		JWTAudience:             viper.GetString("JWT_AUDIENCE"),
+
+		RefreshTokenExpiryHours: viper.GetInt("REFRESH_TOKEN_EXPIRY_HOURS"),
+		RevocationSyncSeconds:   viper.GetInt("REVOCATION_SYNC_SECONDS"),
+
+		ServiceClients: splitPairs(viper.GetString("SERVICE_CLIENTS")),
+
+		MFAIssuer:             viper.GetString("MFA_ISSUER"),
+		MFATokenExpiryMinutes: viper.GetInt("MFA_TOKEN_EXPIRY_MINUTES"),
+
+		MailerDriver: viper.GetString("MAILER_DRIVER"),
+		MailFrom:     viper.GetString("MAIL_FROM"),
+		MailFileDir:  viper.GetString("MAIL_FILE_DIR"),
+		SMTPHost:     viper.GetString("SMTP_HOST"),
+		SMTPPort:     viper.GetString("SMTP_PORT"),
+		SMTPUsername: viper.GetString("SMTP_USERNAME"),
+		SMTPPassword: viper.GetString("SMTP_PASSWORD"),
+
+		EmailVerificationURL:         viper.GetString("EMAIL_VERIFICATION_URL"),
+		EmailVerificationExpiryHours: viper.GetInt("EMAIL_VERIFICATION_EXPIRY_HOURS"),
+		RequireEmailVerification:     viper.GetBool("REQUIRE_EMAIL_VERIFICATION"),
+
+		EmailResendCooldownSeconds: viper.GetInt("EMAIL_RESEND_COOLDOWN_SECONDS"),
+		EmailMaxSendsPerHour:       viper.GetInt("EMAIL_MAX_SENDS_PER_HOUR"),
+
+		SMSDriver:             viper.GetString("SMS_DRIVER"),
+		PhoneOTPExpiryMinutes: viper.GetInt("PHONE_OTP_EXPIRY_MINUTES"),
+		PhoneOTPMaxAttempts:   viper.GetInt("PHONE_OTP_MAX_ATTEMPTS"),
+
+		PasswordResetExpiryMinutes:   viper.GetInt("PASSWORD_RESET_EXPIRY_MINUTES"),
+		PasswordResetMaxAttempts:     viper.GetInt("PASSWORD_RESET_MAX_ATTEMPTS"),
+		PasswordResetCooldownSeconds: viper.GetInt("PASSWORD_RESET_COOLDOWN_SECONDS"),
+		PasswordResetMaxSendsPerHour: viper.GetInt("PASSWORD_RESET_MAX_SENDS_PER_HOUR"),
+
+		MagicLinkURL:           viper.GetString("MAGIC_LINK_URL"),
+		MagicLinkExpiryMinutes: viper.GetInt("MAGIC_LINK_EXPIRY_MINUTES"),
+
+		PhoneOTPLoginAutoRegister:     viper.GetBool("PHONE_OTP_LOGIN_AUTO_REGISTER"),
+		PhoneOTPResendCooldownSeconds: viper.GetInt("PHONE_OTP_RESEND_COOLDOWN_SECONDS"),
+		PhoneOTPMaxSendsPerHour:       viper.GetInt("PHONE_OTP_MAX_SENDS_PER_HOUR"),
+
+		LockoutThreshold:   viper.GetInt("LOCKOUT_THRESHOLD"),
+		LockoutBaseSeconds: viper.GetInt("LOCKOUT_BASE_SECONDS"),
+		LockoutMaxSeconds:  viper.GetInt("LOCKOUT_MAX_SECONDS"),
+
+		HardenedAuthErrors: viper.GetBool("HARDENED_AUTH_ERRORS"),
+
+		RateLimitDriver:        viper.GetString("RATE_LIMIT_DRIVER"),
+		RateLimitsByIP:         splitPairs(viper.GetString("RATE_LIMITS_BY_IP")),
+		RateLimitsByIdentifier: splitPairs(viper.GetString("RATE_LIMITS_BY_IDENTIFIER")),
+
+		TrustedProxies: splitList(viper.GetString("TRUSTED_PROXIES")),
+
+		PasswordHashAlgorithm: viper.GetString("PASSWORD_HASH_ALGORITHM"),
+		Argon2Memory:          viper.GetInt("ARGON2_MEMORY_KIB"),
+		Argon2Iterations:      viper.GetInt("ARGON2_ITERATIONS"),
+		Argon2Parallelism:     viper.GetInt("ARGON2_PARALLELISM"),
+		BcryptCost:            viper.GetInt("BCRYPT_COST"),
+
+		PasswordMinLength:      viper.GetInt("PASSWORD_MIN_LENGTH"),
+		PasswordMaxLength:      viper.GetInt("PASSWORD_MAX_LENGTH"),
+		PasswordMinCharClasses: viper.GetInt("PASSWORD_MIN_CHAR_CLASSES"),
+		PasswordMinEntropyBits: viper.GetInt("PASSWORD_MIN_ENTROPY_BITS"),
+		BreachedPasswordsFile:  viper.GetString("BREACHED_PASSWORDS_FILE"),
+
+		WebAuthnRPID:           viper.GetString("WEBAUTHN_RP_ID"),
+		WebAuthnRPName:         viper.GetString("WEBAUTHN_RP_NAME"),
+		WebAuthnOrigins:        splitList(viper.GetString("WEBAUTHN_ORIGINS")),
+		WebAuthnTimeoutSeconds: viper.GetInt("WEBAUTHN_TIMEOUT_SECONDS"),
+
+		OIDCProviders:          loadOIDCProviders(viper.GetString("OIDC_PROVIDERS")),
+		OIDCRedirectURL:        viper.GetString("OIDC_REDIRECT_URL"),
+		OIDCStateExpiryMinutes: viper.GetInt("OIDC_STATE_EXPIRY_MINUTES"),
+
+		APIKeyMaxPerUser: viper.GetInt("API_KEY_MAX_PER_USER"),
+
+		DefaultRoles: splitList(viper.GetString("DEFAULT_ROLES")),
+
+		ImpersonationExpiryMinutes: viper.GetInt("IMPERSONATION_EXPIRY_MINUTES"),
+	}
+
+	if value := viper.GetString("JWT_ACCEPT_HS256_UNTIL"); value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.Fatalf("Invalid JWT_ACCEPT_HS256_UNTIL, expected RFC 3339: %v", err)
+		}
+		config.JWTAcceptHS256Until = until
+	}
+
+	if viper.GetString("JWT_EXPIRY_HOURS") != "" {
+		log.Printf("JWT_EXPIRY_HOURS is no longer read, set JWT_EXPIRY_MINUTES instead")
+	}
+	if config.JWTExpiryMinutes == 0 {
+		config.JWTExpiryMinutes = 15
+	}
+
+	if config.JWTIssuer == "" {
+		config.JWTIssuer = "tutuplapak-user"
+	}
+
+	if config.JWTAudience == "" {
+		config.JWTAudience = "tutuplapak"
+	}
+
+	if config.RefreshTokenExpiryHours == 0 {
+		config.RefreshTokenExpiryHours = 24 * 30
+	}
+
+	if config.RevocationSyncSeconds == 0 {
+		config.RevocationSyncSeconds = 30
+	}
+
+	if config.MFAIssuer == "" {
+		config.MFAIssuer = "TutupLapak"
+	}
+
+	if config.MFATokenExpiryMinutes == 0 {
+		config.MFATokenExpiryMinutes = 5
+	}
+
+	if config.MailFrom == "" {
+		config.MailFrom = "no-reply@tutuplapak.local"
+	}
+
+	if config.MailFileDir == "" {
+		config.MailFileDir = "tmp/mail"
+	}
+
+	if config.SMTPPort == "" {
+		config.SMTPPort = "587"
+	}
+
+	if config.EmailVerificationURL == "" {
+		config.EmailVerificationURL = "http://localhost:8080/verify-email"
+	}
+
+	if config.EmailVerificationExpiryHours == 0 {
+		config.EmailVerificationExpiryHours = 24
+	}
+
+	if config.EmailResendCooldownSeconds == 0 {
+		config.EmailResendCooldownSeconds = 60
+	}
+
+	if config.EmailMaxSendsPerHour == 0 {
+		config.EmailMaxSendsPerHour = 5
+	}
+
+	if config.PhoneOTPExpiryMinutes == 0 {
+		config.PhoneOTPExpiryMinutes = 10
+	}
+
+	if config.PhoneOTPMaxAttempts == 0 {
+		config.PhoneOTPMaxAttempts = 5
+	}
+
+	if config.PasswordResetExpiryMinutes == 0 {
+		config.PasswordResetExpiryMinutes = 15
+	}
+
+	if config.PasswordResetMaxAttempts == 0 {
+		config.PasswordResetMaxAttempts = 5
+	}
+
+	if config.PasswordResetCooldownSeconds == 0 {
+		config.PasswordResetCooldownSeconds = 60
+	}
+
+	if config.PasswordResetMaxSendsPerHour == 0 {
+		config.PasswordResetMaxSendsPerHour = 5
+	}
+
+	if config.MagicLinkURL == "" {
+		config.MagicLinkURL = "http://localhost:8080/login/link"
+	}
+
+	if config.MagicLinkExpiryMinutes == 0 {
+		config.MagicLinkExpiryMinutes = 15
+	}
+
+	if config.PhoneOTPResendCooldownSeconds == 0 {
+		config.PhoneOTPResendCooldownSeconds = 60
+	}
+
+	if config.PhoneOTPMaxSendsPerHour == 0 {
+		config.PhoneOTPMaxSendsPerHour = 5
+	}
+
+	if config.LockoutThreshold == 0 {
+		config.LockoutThreshold = 5
+	}
+
+	if config.LockoutBaseSeconds == 0 {
+		config.LockoutBaseSeconds = 60
+	}
+
+	if config.LockoutMaxSeconds == 0 {
+		config.LockoutMaxSeconds = 24 * 60 * 60
+	}
+
+	if config.PasswordHashAlgorithm == "" {
+		config.PasswordHashAlgorithm = "argon2id"
+	}
+
+	if config.Argon2Memory == 0 {
+		config.Argon2Memory = 19 * 1024
+	}
+
+	if config.Argon2Iterations == 0 {
+		config.Argon2Iterations = 2
+	}
+
+	if config.Argon2Parallelism == 0 {
+		config.Argon2Parallelism = 1
+	}
+
+	if config.BcryptCost == 0 {
+		config.BcryptCost = 10
+	}
+
+	if config.PasswordMinLength == 0 {
+		config.PasswordMinLength = 8
+	}
+
+	if config.PasswordMaxLength == 0 {
+		config.PasswordMaxLength = 32
+	}
+
+	if config.PasswordMinCharClasses == 0 {
+		config.PasswordMinCharClasses = 2
+	}
+
+	if config.PasswordMinEntropyBits == 0 {
+		config.PasswordMinEntropyBits = 40
+	}
+
+	if config.WebAuthnRPID == "" {
+		config.WebAuthnRPID = "localhost"
+	}
+
+	if config.WebAuthnRPName == "" {
+		config.WebAuthnRPName = "TutupLapak"
+	}
+
+	if len(config.WebAuthnOrigins) == 0 {
+		config.WebAuthnOrigins = []string{"http://localhost:8080"}
+	}
+
+	if config.WebAuthnTimeoutSeconds == 0 {
+		config.WebAuthnTimeoutSeconds = 5 * 60
+	}
+
+	if config.OIDCRedirectURL == "" {
+		config.OIDCRedirectURL = "http://localhost:8080/login/oidc/callback"
 	}
 
-	if config.JWTExpiryHours == 0 {
-		config.JWTExpiryHours = 24
+	if config.OIDCStateExpiryMinutes == 0 {
+		config.OIDCStateExpiryMinutes = 10
+	}
+
+	if config.APIKeyMaxPerUser == 0 {
+		config.APIKeyMaxPerUser = 20
+	}
+
+	if len(config.DefaultRoles) == 0 {
+		config.DefaultRoles = []string{"buyer"}
+	}
+
+	if config.ImpersonationExpiryMinutes == 0 {
+		config.ImpersonationExpiryMinutes = 15
+	}
+
+	for route, limit := range defaultRateLimitsByIP {
+		if _, ok := config.RateLimitsByIP[route]; !ok {
+			config.RateLimitsByIP[route] = limit
+		}
+	}
+
+	for route, limit := range defaultRateLimitsByIdentifier {
+		if _, ok := config.RateLimitsByIdentifier[route]; !ok {
+			config.RateLimitsByIdentifier[route] = limit
+		}
 	}
 
 	return config
 }
+
+// splitList parses a comma separated environment value, dropping blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitPairs parses a comma separated list of key:value entries.
+func splitPairs(value string) map[string]string {
+	pairs := make(map[string]string)
+	for _, item := range splitList(value) {
+		key, val, found := strings.Cut(item, ":")
+		if found && key != "" {
+			pairs[key] = val
+		}
+	}
+	return pairs
+}
+
+// loadOIDCProviders reads OIDC_PROVIDERS as name:issuer entries, e.g.
+// "google:https://accounts.google.com". The credentials of each provider
+// come from OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET.
+func loadOIDCProviders(value string) map[string]OIDCProvider {
+	providers := make(map[string]OIDCProvider)
+	for name, issuer := range splitPairs(value) {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers[name] = OIDCProvider{
+			Issuer:       issuer,
+			ClientID:     viper.GetString(prefix + "CLIENT_ID"),
+			ClientSecret: viper.GetString(prefix + "CLIENT_SECRET"),
+		}
+	}
+	return providers
+}
@@ -0,0 +1,97 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoginAccountLocked(t *testing.T) {
+	mockAuthService := new(services.AuthServiceMock)
+	controller := controllers.NewAuthController(mockAuthService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login/email", controller.LoginWithEmail)
+	router.POST("/v1/login/phone", controller.LoginWithPhone)
+
+	doRequest := func(path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("423 Locked - Email Login With Retry-After", func(t *testing.T) {
+		mockAuthService.On("LoginWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, &services.AccountLockedError{RetryAfter: 2 * time.Minute}).Once()
+
+		resp := doRequest("/v1/login/email", map[string]string{"email": "name@name.com", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusLocked, resp.Code)
+		assert.Equal(t, "120", resp.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"account is temporarily locked after too many failed logins"}`, resp.Body.String())
+	})
+
+	t.Run("423 Locked - Phone Login", func(t *testing.T) {
+		mockAuthService.On("LoginWithPhone", "+6281234567890", "asdfasdf", mock.Anything).
+			Return(nil, nil, &services.AccountLockedError{RetryAfter: 1500 * time.Millisecond}).Once()
+
+		resp := doRequest("/v1/login/phone", map[string]string{"phone": "+6281234567890", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusLocked, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("Retry-After"))
+	})
+}
+
+func TestUnlockUser(t *testing.T) {
+	mockAuthService := new(services.AuthServiceMock)
+	controller := controllers.NewAdminController(mockAuthService)
+
+	router := utils.SetupRouter()
+	clients := map[string]string{"support": "support-secret"}
+	router.POST("/v1/internal/users/:id/unlock", middleware.RequireServiceCredential(clients), controller.UnlockUser)
+
+	doRequest := func(path string, withCredential bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if withCredential {
+			req.SetBasicAuth("support", "support-secret")
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("204 No Content - Unlocked", func(t *testing.T) {
+		mockAuthService.On("UnlockUser", 7).Return(nil).Once()
+
+		resp := doRequest("/v1/internal/users/7/unlock", true)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("404 Not Found - Unknown User", func(t *testing.T) {
+		mockAuthService.On("UnlockUser", 8).Return(services.ErrUserNotFound).Once()
+
+		resp := doRequest("/v1/internal/users/8/unlock", true)
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Missing Service Credential", func(t *testing.T) {
+		resp := doRequest("/v1/internal/users/7/unlock", false)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+}
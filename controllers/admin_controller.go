@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminController struct {
+	authService services.AuthService
+}
+
+func NewAdminController(authService services.AuthService) *AdminController {
+	return &AdminController{authService: authService}
+}
+
+func (c *AdminController) UnlockUser(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	if err := c.authService.UnlockUser(userID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.RespondError(ctx, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService services.APIKeyService
+}
+
+type APIKeyResp struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResp is the only response that carries the key itself.
+type CreateAPIKeyResp struct {
+	APIKeyResp
+	Key string `json:"key"`
+}
+
+func NewAPIKeyController(apiKeyService services.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService}
+}
+
+func (c *APIKeyController) Create(ctx *gin.Context) {
+
+	var req struct {
+		Name      string     `json:"name" binding:"required,max=100"`
+		Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+
+	key, rawKey, err := c.apiKeyService.CreateKey(user, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownScope), errors.Is(err, services.ErrAPIKeyExpiry):
+			utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrTooManyAPIKeys):
+			utils.RespondError(ctx, http.StatusConflict, err.Error())
+		default:
+			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		}
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusCreated, CreateAPIKeyResp{
+		APIKeyResp: toAPIKeyResp(*key),
+		Key:        rawKey,
+	})
+}
+
+func (c *APIKeyController) List(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	keys, err := c.apiKeyService.ListKeys(user.ID)
+	if err != nil {
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	resp := make([]APIKeyResp, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, toAPIKeyResp(key))
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, resp)
+}
+
+func (c *APIKeyController) Revoke(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	keyID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid api key id")
+		return
+	}
+
+	if err := c.apiKeyService.RevokeKey(user.ID, keyID); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			utils.RespondError(ctx, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func toAPIKeyResp(key models.APIKey) APIKeyResp {
+	resp := APIKeyResp{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    strings.Fields(key.Scopes),
+		CreatedAt: key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		resp.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		resp.LastUsedAt = &key.LastUsedAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,177 @@
+package controllers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeys creates a key through the API and then calls the API with it,
+// through the real service with only storage mocked.
+func TestAPIKeys(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockAPIKeyRepo := new(repositories.APIKeyRepositoryMock)
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	service := services.NewAPIKeyService(mockAPIKeyRepo, mockUserRepo, config.Config{APIKeyMaxPerUser: 2})
+	controller := controllers.NewAPIKeyController(service)
+	userController := controllers.NewUserController()
+
+	router := utils.SetupRouter()
+	protected := router.Group("/v1", middleware.Authenticate(mockTokenService, service))
+	protected.GET("/user", middleware.RequireScope(services.ScopeProfileRead), userController.Profile)
+	userLogin := protected.Group("", middleware.RequireUserLogin())
+	userLogin.GET("/user/api-keys", controller.List)
+	userLogin.POST("/user/api-keys", controller.Create)
+	userLogin.DELETE("/user/api-keys/:id", controller.Revoke)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	claims := &utils.Claims{LoginMethod: "email"}
+	claims.Subject = "1"
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, claims, nil)
+	mockUserRepo.On("FindByID", 1).Return(user, nil)
+
+	doRequest := func(method, path, authorization string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", authorization)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	var stored *models.APIKey
+	var rawKey string
+
+	t.Run("201 Created - Create Key", func(t *testing.T) {
+		mockAPIKeyRepo.On("ListActiveByUser", 1).Return([]models.APIKey{}, nil).Once()
+		mockAPIKeyRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(0).(*models.APIKey)
+			stored.ID = 5
+			stored.CreatedAt = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		}).Return(nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/user/api-keys", "Bearer token123", map[string]interface{}{
+			"name":       "nightly export",
+			"scopes":     []string{"profile:read", "profile:read"},
+			"expires_at": "2999-01-01T00:00:00Z",
+		})
+
+		require.Equal(t, http.StatusCreated, resp.Code)
+		var created controllers.CreateAPIKeyResp
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+		rawKey = created.Key
+		assert.True(t, strings.HasPrefix(rawKey, "tlk_"+stored.Prefix+"_"))
+		assert.Equal(t, utils.HashToken(strings.TrimPrefix(rawKey, "tlk_"+stored.Prefix+"_")), stored.SecretHash)
+		assert.Equal(t, "profile:read", stored.Scopes)
+		assert.Equal(t, []string{"profile:read"}, created.Scopes)
+		assert.Equal(t, time.Date(2999, 1, 1, 0, 0, 0, 0, time.UTC), stored.ExpiresAt.Time)
+	})
+
+	t.Run("200 OK - Call The API With The Key", func(t *testing.T) {
+		mockAPIKeyRepo.On("FindByPrefix", stored.Prefix).Return(stored, nil)
+		mockAPIKeyRepo.On("Touch", 5, mock.Anything).Return(nil).Once()
+
+		resp := doRequest(http.MethodGet, "/v1/user", "ApiKey "+rawKey, nil)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"email":"name@name.com"`)
+		mockAPIKeyRepo.AssertExpectations(t)
+	})
+
+	t.Run("403 Forbidden - Key Cannot Manage Keys", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/user/api-keys", "ApiKey "+rawKey, map[string]interface{}{
+			"name":   "escalate",
+			"scopes": []string{"sessions:read"},
+		})
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Wrong Secret", func(t *testing.T) {
+		resp := doRequest(http.MethodGet, "/v1/user", "ApiKey tlk_"+stored.Prefix+"_guess", nil)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Expired Key", func(t *testing.T) {
+		stored.ExpiresAt = sql.NullTime{Time: time.Now().UTC().Add(-time.Second), Valid: true}
+		defer func() { stored.ExpiresAt = sql.NullTime{} }()
+
+		resp := doRequest(http.MethodGet, "/v1/user", "ApiKey "+rawKey, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired api key"}`, resp.Body.String())
+	})
+
+	t.Run("200 OK - List Keys", func(t *testing.T) {
+		mockAPIKeyRepo.On("ListActiveByUser", 1).Return([]models.APIKey{*stored}, nil).Once()
+
+		resp := doRequest(http.MethodGet, "/v1/user/api-keys", "Bearer token123", nil)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.NotContains(t, resp.Body.String(), `"key"`)
+		assert.Contains(t, resp.Body.String(), `"name":"nightly export"`)
+	})
+
+	t.Run("400 Bad Request - Unknown Scope", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/user/api-keys", "Bearer token123", map[string]interface{}{
+			"name":   "admin",
+			"scopes": []string{"admin"},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"unknown scope \"admin\""}`, resp.Body.String())
+	})
+
+	t.Run("409 Conflict - Too Many Keys", func(t *testing.T) {
+		mockAPIKeyRepo.On("ListActiveByUser", 1).Return([]models.APIKey{{ID: 5}, {ID: 6}}, nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/user/api-keys", "Bearer token123", map[string]interface{}{
+			"name":   "third",
+			"scopes": []string{"profile:read"},
+		})
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("204 No Content - Revoke Key", func(t *testing.T) {
+		mockAPIKeyRepo.On("Revoke", 1, 5).Return(true, nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/user/api-keys/5", "Bearer token123", nil)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("404 Not Found - Revoke Unknown Key", func(t *testing.T) {
+		mockAPIKeyRepo.On("Revoke", 1, 99).Return(false, nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/user/api-keys/99", "Bearer token123", nil)
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Revoked Key", func(t *testing.T) {
+		stored.RevokedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+
+		resp := doRequest(http.MethodGet, "/v1/user", "ApiKey "+rawKey, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+}
@@ -2,9 +2,12 @@ package controllers
 
 import (
 	"errors"
+	"go-tutuplapak-user/models"
 	"go-tutuplapak-user/services"
 	"go-tutuplapak-user/utils"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,26 +17,42 @@ type AuthController struct {
 }
 
 type LoginRegisterPhoneResp struct {
-	Phone string `json:"phone"`
-	Email string `json:"email"`
-	Token string `json:"token"`
+	Phone        string `json:"phone"`
+	Email        string `json:"email"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 type LoginRegisterEmailResp struct {
-	Email string `json:"email"`
-	Phone string `json:"phone"`
-	Token string `json:"token"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type LoginResp struct {
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type MFARequiredResp struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
 }
 
 func NewAuthController(authService services.AuthService) *AuthController {
 	return &AuthController{authService: authService}
 }
 
-func (c *AuthController) LoginWithEmail(ctx *gin.Context) {
+// Login accepts either an email address or an E.164 phone number in a single
+// identifier field. The email and phone endpoints are kept for older clients.
+func (c *AuthController) Login(ctx *gin.Context) {
 
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=8,max=32"`
+		Identifier string `json:"identifier" binding:"required"`
+		Password   string `json:"password" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -41,30 +60,31 @@ func (c *AuthController) LoginWithEmail(ctx *gin.Context) {
 		return
 	}
 
-	user, token, err := c.authService.LoginWithEmail(req.Email, req.Password)
-	if err != nil {
-		if errors.Is(err, utils.ErrInternal) {
-			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
-			return
-		}
-		utils.RespondError(ctx, http.StatusNotFound, err.Error())
-		return
+	user, tokens, err := c.authService.Login(req.Identifier, req.Password, clientInfo(ctx))
+	respondLogin(ctx, user, tokens, err)
+}
+
+func (c *AuthController) LoginWithEmail(ctx *gin.Context) {
+
+	var req struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"required"`
 	}
 
-	userResponse := utils.ToUserResponse(user)
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
 
-	utils.RespondJSON(ctx, http.StatusOK, LoginRegisterEmailResp{
-		Email: userResponse.Email,
-		Phone: userResponse.Phone,
-		Token: token,
-	})
+	user, tokens, err := c.authService.LoginWithEmail(req.Email, req.Password, clientInfo(ctx))
+	respondLogin(ctx, user, tokens, err)
 }
 
 func (c *AuthController) LoginWithPhone(ctx *gin.Context) {
 
 	var req struct {
-		Phone    string `json:"phone" binding:"required"`
-		Password string `json:"password" binding:"required,min=8,max=32"`
+		Phone    string `json:"phone" binding:"required,startswith=+"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -72,30 +92,15 @@ func (c *AuthController) LoginWithPhone(ctx *gin.Context) {
 		return
 	}
 
-	user, token, err := c.authService.LoginWithPhone(req.Phone, req.Password)
-	if err != nil {
-		if errors.Is(err, utils.ErrInternal) {
-			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
-			return
-		}
-		utils.RespondError(ctx, http.StatusNotFound, err.Error())
-		return
-	}
-
-	userResponse := utils.ToUserResponse(user)
-
-	utils.RespondJSON(ctx, http.StatusOK, LoginRegisterPhoneResp{
-		Phone: userResponse.Phone,
-		Email: userResponse.Email,
-		Token: token,
-	})
+	user, tokens, err := c.authService.LoginWithPhone(req.Phone, req.Password, clientInfo(ctx))
+	respondLogin(ctx, user, tokens, err)
 }
 
 func (c *AuthController) RegisterWithEmail(ctx *gin.Context) {
 
 	var req struct {
 		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=8,max=32"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -103,8 +108,11 @@ func (c *AuthController) RegisterWithEmail(ctx *gin.Context) {
 		return
 	}
 
-	user, token, err := c.authService.RegisterWithEmail(req.Email, req.Password)
+	user, tokens, err := c.authService.RegisterWithEmail(req.Email, req.Password, clientInfo(ctx))
 	if err != nil {
+		if respondRegistrationPending(ctx, err) || respondPasswordPolicy(ctx, err, "password") {
+			return
+		}
 		if errors.Is(err, utils.ErrInternal) {
 			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
 			return
@@ -121,17 +129,18 @@ func (c *AuthController) RegisterWithEmail(ctx *gin.Context) {
 	userResponse := utils.ToUserResponse(user)
 
 	utils.RespondJSON(ctx, http.StatusCreated, LoginRegisterEmailResp{
-		Email: userResponse.Email,
-		Phone: userResponse.Phone,
-		Token: token,
+		Email:        userResponse.Email,
+		Phone:        userResponse.Phone,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	})
 }
 
 func (c *AuthController) RegisterWithPhone(ctx *gin.Context) {
 
 	var req struct {
-		Phone    string `json:"phone" binding:"required"`
-		Password string `json:"password" binding:"required,min=8,max=32"`
+		Phone    string `json:"phone" binding:"required,startswith=+"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -139,8 +148,11 @@ func (c *AuthController) RegisterWithPhone(ctx *gin.Context) {
 		return
 	}
 
-	user, token, err := c.authService.RegisterWithPhone(req.Phone, req.Password)
+	user, tokens, err := c.authService.RegisterWithPhone(req.Phone, req.Password, clientInfo(ctx))
 	if err != nil {
+		if respondRegistrationPending(ctx, err) || respondPasswordPolicy(ctx, err, "password") {
+			return
+		}
 		if errors.Is(err, utils.ErrInternal) {
 			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
 			return
@@ -159,8 +171,89 @@ func (c *AuthController) RegisterWithPhone(ctx *gin.Context) {
 	userResponse := utils.ToUserResponse(user)
 
 	utils.RespondJSON(ctx, http.StatusCreated, LoginRegisterPhoneResp{
-		Phone: userResponse.Phone,
-		Email: userResponse.Email,
-		Token: token,
+		Phone:        userResponse.Phone,
+		Email:        userResponse.Email,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+func clientInfo(ctx *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: ctx.Request.UserAgent(),
+		IPAddress: ctx.ClientIP(),
+	}
+}
+
+// respondLogin writes the outcome of any password login, so every login
+// endpoint answers with the same shape.
+func respondLogin(ctx *gin.Context, user *models.User, tokens *services.TokenPair, err error) {
+	if err != nil {
+		if respondMFARequired(ctx, err) || respondAccountLocked(ctx, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidIdentifier) {
+			utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			utils.RespondError(ctx, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, utils.ErrInternal) {
+			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, LoginResp{
+		Email:        userResponse.Email,
+		Phone:        userResponse.Phone,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	})
 }
+
+// respondRegistrationPending answers a registration that continues through a
+// message sent to the user, as all of them do in hardened mode, and reports
+// whether it did so.
+func respondRegistrationPending(ctx *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrRegistrationPending) {
+		return false
+	}
+
+	utils.RespondJSON(ctx, http.StatusAccepted, MessageResp{Message: err.Error()})
+	return true
+}
+
+// respondMFARequired answers a password login that still needs a second factor
+// and reports whether it did so.
+func respondMFARequired(ctx *gin.Context, err error) bool {
+	var mfaErr *services.MFARequiredError
+	if !errors.As(err, &mfaErr) {
+		return false
+	}
+
+	utils.RespondJSON(ctx, http.StatusAccepted, MFARequiredResp{
+		MFARequired: true,
+		MFAToken:    mfaErr.MFAToken,
+	})
+	return true
+}
+
+// respondAccountLocked answers a login refused by the lockout and reports
+// whether it did so.
+func respondAccountLocked(ctx *gin.Context, err error) bool {
+	var lockedErr *services.AccountLockedError
+	if !errors.As(err, &lockedErr) {
+		return false
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+	utils.RespondError(ctx, http.StatusLocked, err.Error())
+	return true
+}
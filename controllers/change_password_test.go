@@ -0,0 +1,77 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestChangePassword(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockPasswordService := new(services.PasswordServiceMock)
+	controller := controllers.NewPasswordController(mockPasswordService)
+
+	router := utils.SetupRouter()
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.PUT("/user/password", controller.ChangePassword)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, &utils.Claims{LoginMethod: "email"}, nil)
+
+	doRequest := func(reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPut, "/v1/user/password", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Password Changed And New Tokens Issued", func(t *testing.T) {
+		mockPasswordService.On("ChangePassword", user, "email", "oldpassword", "newpassword", mock.Anything).
+			Return(&services.TokenPair{AccessToken: "token456", RefreshToken: "refresh456"}, nil).Once()
+
+		resp := doRequest(map[string]string{"current_password": "oldpassword", "new_password": "newpassword"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"token":"token456", "refresh_token":"refresh456"}`, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Validation Error: Missing New Password", func(t *testing.T) {
+		resp := doRequest(map[string]string{"current_password": "oldpassword"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("423 Locked - Too Many Wrong Current Passwords", func(t *testing.T) {
+		mockPasswordService.On("ChangePassword", user, "email", "guess", "newpassword", mock.Anything).
+			Return(nil, &services.AccountLockedError{RetryAfter: 60 * time.Second}).Once()
+
+		resp := doRequest(map[string]string{"current_password": "guess", "new_password": "newpassword"})
+
+		assert.Equal(t, http.StatusLocked, resp.Code)
+		assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+	})
+
+	t.Run("403 Forbidden - Wrong Current Password", func(t *testing.T) {
+		mockPasswordService.On("ChangePassword", user, "email", "wrongpassword", "newpassword", mock.Anything).
+			Return(nil, services.ErrWrongPassword).Once()
+
+		resp := doRequest(map[string]string{"current_password": "wrongpassword", "new_password": "newpassword"})
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"current password is incorrect"}`, resp.Body.String())
+	})
+}
@@ -0,0 +1,75 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHardenedAuthErrors(t *testing.T) {
+	mockAuthService := new(services.AuthServiceMock)
+	controller := controllers.NewAuthController(mockAuthService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login", controller.Login)
+	router.POST("/v1/login/email", controller.LoginWithEmail)
+	router.POST("/v1/register/email", controller.RegisterWithEmail)
+	router.POST("/v1/register/phone", controller.RegisterWithPhone)
+
+	doRequest := func(path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("401 Unauthorized - Unknown Email", func(t *testing.T) {
+		mockAuthService.On("LoginWithEmail", "nobody@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, services.ErrInvalidCredentials).Once()
+
+		resp := doRequest("/v1/login/email", map[string]string{"email": "nobody@name.com", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid credentials"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Wrong Password Looks The Same", func(t *testing.T) {
+		mockAuthService.On("Login", "+6281234567890", "wrongpass", mock.Anything).
+			Return(nil, nil, services.ErrInvalidCredentials).Once()
+
+		resp := doRequest("/v1/login", map[string]string{"identifier": "+6281234567890", "password": "wrongpass"})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid credentials"}`, resp.Body.String())
+	})
+
+	t.Run("202 Accepted - Email Registration", func(t *testing.T) {
+		mockAuthService.On("RegisterWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, services.ErrRegistrationPending).Once()
+
+		resp := doRequest("/v1/register/email", map[string]string{"email": "name@name.com", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		assert.JSONEq(t, `{"message":"we sent you a message to finish signing up"}`, resp.Body.String())
+	})
+
+	t.Run("202 Accepted - Phone Registration", func(t *testing.T) {
+		mockAuthService.On("RegisterWithPhone", "+6281234567890", "asdfasdf", mock.Anything).
+			Return(nil, nil, services.ErrRegistrationPending).Once()
+
+		resp := doRequest("/v1/register/phone", map[string]string{"phone": "+6281234567890", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		assert.JSONEq(t, `{"message":"we sent you a message to finish signing up"}`, resp.Body.String())
+	})
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImpersonationController struct {
+	impersonationService services.ImpersonationService
+}
+
+type ImpersonationResp struct {
+	UserID    int       `json:"user_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func NewImpersonationController(impersonationService services.ImpersonationService) *ImpersonationController {
+	return &ImpersonationController{impersonationService: impersonationService}
+}
+
+// Start answers with an access token acting as the user. It has no refresh
+// token; the admin starts again once it expires.
+func (c *ImpersonationController) Start(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("userId"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	claims, _ := middleware.CurrentClaims(ctx)
+
+	impersonation, err := c.impersonationService.Start(claims, userID, clientInfo(ctx))
+	if err != nil {
+		respondImpersonationError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, ImpersonationResp{
+		UserID:    userID,
+		Token:     impersonation.AccessToken,
+		ExpiresAt: impersonation.ExpiresAt,
+	})
+}
+
+// Stop ends the impersonation the request's token belongs to.
+func (c *ImpersonationController) Stop(ctx *gin.Context) {
+	claims, _ := middleware.CurrentClaims(ctx)
+
+	if err := c.impersonationService.Stop(claims, clientInfo(ctx)); err != nil {
+		respondImpersonationError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func respondImpersonationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.RespondError(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrImpersonateSelf), errors.Is(err, services.ErrNotImpersonating):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrImpersonateAdmin), errors.Is(err, services.ErrAlreadyImpersonating):
+		utils.RespondError(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, utils.ErrInvalidToken):
+		utils.RespondError(ctx, http.StatusUnauthorized, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
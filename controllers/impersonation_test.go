@@ -0,0 +1,200 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// revokedJTIs stands in for the revocation service, which keeps its state
+// in the database.
+type revokedJTIs map[string]bool
+
+func (r revokedJTIs) Revoke(jti string, _ time.Time) error { r[jti] = true; return nil }
+func (r revokedJTIs) IsRevoked(jti string) bool            { return r[jti] }
+func (r revokedJTIs) Start(time.Duration)                  {}
+
+// TestImpersonation impersonates a user with real tokens and services; only
+// storage and sessions are mocked.
+func TestImpersonation(t *testing.T) {
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	mockRoleRepo := new(repositories.RoleRepositoryMock)
+	mockAuditLogRepo := new(repositories.AuditLogRepositoryMock)
+	mockSessionService := new(services.SessionServiceMock)
+	mockRoleRepo.On("ListRolePermissions").Return(map[string][]string{
+		services.RoleBuyer:  {services.PermissionOrdersCreate},
+		services.RoleSeller: {services.PermissionProductsManage},
+		services.RoleAdmin:  {services.PermissionRolesManage, services.PermissionImpersonate},
+	}, nil)
+
+	cfg := config.Config{
+		JWTIssuer:                  "tutuplapak-user",
+		JWTAudience:                "tutuplapak",
+		DefaultRoles:               []string{services.RoleBuyer},
+		ImpersonationExpiryMinutes: 15,
+	}
+	keys := utils.NewHMACKeySet("secret")
+	roleService, err := services.NewRoleService(mockRoleRepo, mockUserRepo, mockSessionService, cfg)
+	require.NoError(t, err)
+	tokenService := services.NewTokenService(mockUserRepo, nil, mockSessionService, revokedJTIs{}, roleService, keys, cfg)
+	service := services.NewImpersonationService(mockUserRepo, mockAuditLogRepo, tokenService, roleService)
+	controller := controllers.NewImpersonationController(service)
+	userController := controllers.NewUserController()
+
+	router := utils.SetupRouter()
+	router.GET("/v1/user", middleware.Authenticate(tokenService, nil), userController.Profile)
+	router.PUT("/v1/user/password", middleware.Authenticate(tokenService, nil), middleware.RequireUserLogin(), middleware.ForbidImpersonation(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+	admin := router.Group("/v1/admin", middleware.Authenticate(tokenService, nil), middleware.RequireUserLogin())
+	admin.POST("/impersonate/:userId", middleware.RequirePermission(roleService, services.PermissionImpersonate), controller.Start)
+	admin.DELETE("/impersonate", controller.Stop)
+
+	adminUser := &models.User{ID: 1, Email: utils.NewNullableString("admin@name.com")}
+	targetUser := &models.User{ID: 2, Email: utils.NewNullableString("name@name.com")}
+	mockUserRepo.On("FindByID", 1).Return(adminUser, nil)
+	mockUserRepo.On("FindByID", 2).Return(targetUser, nil)
+	mockUserRepo.On("FindByID", 3).Return(&models.User{ID: 3}, nil)
+	mockUserRepo.On("FindByID", 99).Return(nil, nil)
+	mockRoleRepo.On("ListUserRoles", 2).Return([]string{services.RoleSeller}, nil)
+	mockRoleRepo.On("ListUserRoles", 3).Return([]string{services.RoleAdmin}, nil)
+	mockSessionService.On("ValidateSession", 1, 10).Return(nil)
+	mockSessionService.On("ValidateSession", 2, 20).Return(nil)
+
+	accessToken := func(userID, sessionID string, roles ...string) string {
+		claims, err := utils.NewClaims(utils.TokenUseAccess, userID, services.LoginMethodEmail, cfg.JWTIssuer, cfg.JWTAudience, time.Hour)
+		require.NoError(t, err)
+		claims.SessionID = sessionID
+		claims.Roles = roles
+		token, err := utils.GenerateJWT(claims, keys)
+		require.NoError(t, err)
+		return token
+	}
+	adminToken := accessToken("1", "10", services.RoleBuyer, services.RoleAdmin)
+
+	doRequest := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(nil))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = "203.0.113.7:1234"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	var impersonation controllers.ImpersonationResp
+	var startedJTI string
+
+	t.Run("200 OK - Start Impersonation", func(t *testing.T) {
+		mockAuditLogRepo.On("Create", mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			startedJTI = entry.Details
+			return entry.Action == services.AuditImpersonationStarted && entry.ActorID.Int64 == 1 &&
+				entry.TargetUserID.Int64 == 2 && entry.IPAddress == "203.0.113.7"
+		})).Return(nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/admin/impersonate/2", adminToken)
+
+		require.Equal(t, http.StatusOK, resp.Code)
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &impersonation))
+		assert.Equal(t, 2, impersonation.UserID)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), impersonation.ExpiresAt, time.Minute)
+
+		claims, err := utils.ParseJWT(impersonation.Token, keys, cfg.JWTIssuer, cfg.JWTAudience)
+		require.NoError(t, err)
+		assert.Equal(t, "2", claims.Subject)
+		assert.Equal(t, &utils.ActorClaims{Subject: "1"}, claims.Actor)
+		assert.Equal(t, "10", claims.SessionID)
+		assert.Equal(t, []string{services.RoleBuyer, services.RoleSeller}, claims.Roles)
+		assert.Equal(t, services.LoginMethodImpersonation, claims.LoginMethod)
+		assert.Equal(t, "profile:read sessions:read", claims.Scope)
+		assert.Equal(t, claims.ID, startedJTI)
+		mockAuditLogRepo.AssertExpectations(t)
+	})
+
+	t.Run("200 OK - Sees What The User Sees", func(t *testing.T) {
+		resp := doRequest(http.MethodGet, "/v1/user", impersonation.Token)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"email":"name@name.com"`)
+	})
+
+	t.Run("403 Forbidden - Sensitive Action", func(t *testing.T) {
+		resp := doRequest(http.MethodPut, "/v1/user/password", impersonation.Token)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"this action is not allowed while impersonating a user"}`, resp.Body.String())
+		assert.Equal(t, http.StatusNoContent, doRequest(http.MethodPut, "/v1/user/password", adminToken).Code)
+	})
+
+	t.Run("403 Forbidden - Impersonation Token Cannot Impersonate", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/admin/impersonate/3", impersonation.Token)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("403 Forbidden - Target Is An Admin", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/admin/impersonate/3", adminToken)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"users who can manage roles or impersonate cannot be impersonated"}`, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Impersonate Yourself", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, doRequest(http.MethodPost, "/v1/admin/impersonate/1", adminToken).Code)
+	})
+
+	t.Run("404 Not Found - Unknown User", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, doRequest(http.MethodPost, "/v1/admin/impersonate/99", adminToken).Code)
+	})
+
+	t.Run("403 Forbidden - Not An Admin", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/admin/impersonate/3", accessToken("2", "20", services.RoleBuyer, services.RoleSeller))
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"you do not have permission to do this"}`, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Stop Without Impersonating", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, doRequest(http.MethodDelete, "/v1/admin/impersonate", adminToken).Code)
+	})
+
+	t.Run("401 Unauthorized - Admin Session Ended", func(t *testing.T) {
+		mockSessionService.On("ValidateSession", 1, 11).Return(nil).Once()
+		mockAuditLogRepo.On("Create", mock.Anything).Return(nil).Once()
+		resp := doRequest(http.MethodPost, "/v1/admin/impersonate/2", accessToken("1", "11", services.RoleAdmin))
+		require.Equal(t, http.StatusOK, resp.Code)
+		var other controllers.ImpersonationResp
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &other))
+
+		mockSessionService.On("ValidateSession", 1, 11).Return(services.ErrSessionRevoked).Once()
+
+		assert.Equal(t, http.StatusUnauthorized, doRequest(http.MethodGet, "/v1/user", other.Token).Code)
+	})
+
+	t.Run("204 No Content - Stop Impersonation", func(t *testing.T) {
+		mockAuditLogRepo.On("Create", mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == services.AuditImpersonationStopped && entry.ActorID.Int64 == 1 &&
+				entry.TargetUserID.Int64 == 2 && entry.Details == startedJTI
+		})).Return(nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/admin/impersonate", impersonation.Token)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		mockAuditLogRepo.AssertExpectations(t)
+		assert.Equal(t, http.StatusUnauthorized, doRequest(http.MethodGet, "/v1/user", impersonation.Token).Code)
+	})
+}
@@ -0,0 +1,131 @@
+package controllers_test
+
+import (
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntrospect(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	controller := controllers.NewTokenController(mockTokenService)
+
+	router := utils.SetupRouter()
+	clients := map[string]string{"product": "product-secret"}
+	router.POST("/v1/internal/introspect", middleware.RequireServiceCredential(clients), controller.Introspect)
+
+	doRequest := func(token string, withCredential bool) *httptest.ResponseRecorder {
+		form := url.Values{"token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/v1/internal/introspect", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if withCredential {
+			req.SetBasicAuth("product", "product-secret")
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Active Token", func(t *testing.T) {
+		issuedAt := time.Unix(1700000000, 0)
+		claims := &utils.Claims{LoginMethod: "phone", Scope: "profile:read sessions:read", Roles: []string{"buyer", "seller"}}
+		claims.ID = "jti123"
+		claims.Subject = "7"
+		claims.Issuer = "tutuplapak-user"
+		claims.Audience = jwt.ClaimStrings{"tutuplapak"}
+		claims.IssuedAt = jwt.NewNumericDate(issuedAt)
+		claims.ExpiresAt = jwt.NewNumericDate(issuedAt.Add(time.Hour))
+
+		mockTokenService.On("VerifyAccessToken", "token123").
+			Return(&models.User{ID: 7, Phone: utils.NewNullableString("+628123456789")}, claims, nil)
+
+		resp := doRequest("token123", true)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{
+			"active": true,
+			"sub": "7",
+			"scope": "profile:read sessions:read",
+			"token_type": "Bearer",
+			"iss": "tutuplapak-user",
+			"aud": ["tutuplapak"],
+			"exp": 1700003600,
+			"iat": 1700000000,
+			"jti": "jti123",
+			"login_method": "phone",
+			"roles": ["buyer", "seller"],
+			"phone": "+628123456789"
+		}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("200 OK - Revoked Token Is Inactive", func(t *testing.T) {
+		mockTokenService.On("VerifyAccessToken", "revoked").
+			Return(nil, nil, services.ErrTokenRevoked)
+
+		resp := doRequest("revoked", true)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"active": false}`, resp.Body.String())
+	})
+
+	t.Run("200 OK - Impersonation Token Names The Admin", func(t *testing.T) {
+		claims := &utils.Claims{LoginMethod: services.LoginMethodImpersonation, Actor: &utils.ActorClaims{Subject: "1"}}
+		claims.Subject = "7"
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(1700000000, 0))
+		claims.ExpiresAt = jwt.NewNumericDate(time.Unix(1700000900, 0))
+
+		mockTokenService.On("VerifyAccessToken", "impersonation123").
+			Return(&models.User{ID: 7}, claims, nil)
+
+		resp := doRequest("impersonation123", true)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Contains(t, resp.Body.String(), `"act":{"sub":"1"}`)
+	})
+
+	t.Run("400 Bad Request - Missing Token", func(t *testing.T) {
+		resp := doRequest("", true)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Missing Service Credential", func(t *testing.T) {
+		resp := doRequest("token123", false)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.NotEmpty(t, resp.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("401 Unauthorized - Wrong Service Secret", func(t *testing.T) {
+		form := url.Values{"token": {"token123"}}
+		req := httptest.NewRequest(http.MethodPost, "/v1/internal/introspect", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("product", "wrong")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		mockTokenService.On("VerifyAccessToken", "broken").
+			Return(nil, nil, utils.ErrInternal)
+
+		resp := doRequest("broken", true)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLogin(t *testing.T) {
+	mockAuthServiceMock := new(services.AuthServiceMock)
+	controller := controllers.NewAuthController(mockAuthServiceMock)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login", controller.Login)
+
+	doRequest := func(reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/v1/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Email Identifier", func(t *testing.T) {
+		mockAuthServiceMock.On("Login", "name@name.com", "asdfasdf", mock.Anything).
+			Return(&models.User{Email: utils.NewNullableString("name@name.com")}, &services.TokenPair{AccessToken: "token123", RefreshToken: "refresh123"}, nil).Once()
+
+		resp := doRequest(map[string]string{"identifier": "name@name.com", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"email":"name@name.com", "phone":"", "token":"token123", "refresh_token":"refresh123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("200 OK - Phone Identifier", func(t *testing.T) {
+		mockAuthServiceMock.On("Login", "+6289898874", "asdfasdf", mock.Anything).
+			Return(&models.User{Phone: utils.NewNullableString("+6289898874")}, &services.TokenPair{AccessToken: "token123"}, nil).Once()
+
+		resp := doRequest(map[string]string{"identifier": "+6289898874", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"email":"", "phone":"+6289898874", "token":"token123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("202 Accepted - MFA Required", func(t *testing.T) {
+		mockAuthServiceMock.On("Login", "mfa@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, &services.MFARequiredError{MFAToken: "mfa123"}).Once()
+
+		resp := doRequest(map[string]string{"identifier": "mfa@name.com", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		assert.JSONEq(t, `{"mfa_required":true, "mfa_token":"mfa123"}`, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Unrecognised Identifier", func(t *testing.T) {
+		mockAuthServiceMock.On("Login", "not-an-identifier", "asdfasdf", mock.Anything).
+			Return(nil, nil, services.ErrInvalidIdentifier).Once()
+
+		resp := doRequest(map[string]string{"identifier": "not-an-identifier", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"identifier must be an email address or phone number"}`, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Validation Error: Required", func(t *testing.T) {
+		resp := doRequest(map[string]string{"identifier": "", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("404 Not Found - Wrong Password", func(t *testing.T) {
+		mockAuthServiceMock.On("Login", "name@name.com", "wrongpass", mock.Anything).
+			Return(nil, nil, errors.New("invalid email or password")).Once()
+
+		resp := doRequest(map[string]string{"identifier": "name@name.com", "password": "wrongpass"})
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		mockAuthServiceMock.On("Login", "broken@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, utils.ErrInternal).Once()
+
+		resp := doRequest(map[string]string{"identifier": "broken@name.com", "password": "asdfasdf"})
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
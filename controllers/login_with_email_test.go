@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestLoginWithEmail(t *testing.T) {
@@ -26,8 +27,8 @@ func TestLoginWithEmail(t *testing.T) {
 		reqBody := map[string]string{"email": "name@name.com", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthService.On("LoginWithEmail", "name@name.com", "asdfasdf").
-			Return(&models.User{Email: utils.NewNullableString("name@name.com")}, "token123", nil)
+		mockAuthService.On("LoginWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(&models.User{Email: utils.NewNullableString("name@name.com")}, &services.TokenPair{AccessToken: "token123"}, nil)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -89,8 +90,8 @@ func TestLoginWithEmail(t *testing.T) {
 		reqBody := map[string]string{"email": "notfound@name.com", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthService.On("LoginWithEmail", "notfound@name.com", "asdfasdf").
-			Return(nil, "", errors.New("email not found"))
+		mockAuthService.On("LoginWithEmail", "notfound@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, errors.New("email not found"))
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -113,8 +114,8 @@ func TestLoginWithEmail(t *testing.T) {
 		reqBody := map[string]string{"email": "name@name.com", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthService.On("LoginWithEmail", "name@name.com", "asdfasdf").
-			Return(nil, "", utils.ErrInternal)
+		mockAuthService.On("LoginWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, utils.ErrInternal)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
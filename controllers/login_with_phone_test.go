@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestLoginWithPhone(t *testing.T) {
@@ -26,8 +27,8 @@ func TestLoginWithPhone(t *testing.T) {
 		reqBody := map[string]string{"phone": "+6289898874", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("LoginWithPhone", "+6289898874", "asdfasdf").
-			Return(&models.User{Phone: utils.NewNullableString("+6289898874")}, "token123", nil)
+		mockAuthServiceMock.On("LoginWithPhone", "+6289898874", "asdfasdf", mock.Anything).
+			Return(&models.User{Phone: utils.NewNullableString("+6289898874")}, &services.TokenPair{AccessToken: "token123"}, nil)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/login/phone", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -89,8 +90,8 @@ func TestLoginWithPhone(t *testing.T) {
 		reqBody := map[string]string{"phone": "+6743656478", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("LoginWithPhone", "+6743656478", "asdfasdf").
-			Return(nil, "", errors.New("phone not found"))
+		mockAuthServiceMock.On("LoginWithPhone", "+6743656478", "asdfasdf", mock.Anything).
+			Return(nil, nil, errors.New("phone not found"))
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/login/phone", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -113,8 +114,8 @@ func TestLoginWithPhone(t *testing.T) {
 		reqBody := map[string]string{"phone": "+6743656478", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("LoginWithPhone", "+6743656478", "asdfasdf").
-			Return(nil, "", utils.ErrInternal)
+		mockAuthServiceMock.On("LoginWithPhone", "+6743656478", "asdfasdf", mock.Anything).
+			Return(nil, nil, utils.ErrInternal)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/login/phone", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -0,0 +1,95 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogout(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	controller := controllers.NewTokenController(mockTokenService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/logout", middleware.Authenticate(mockTokenService, nil), controller.Logout)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	claims := &utils.Claims{LoginMethod: "email"}
+	claims.ID = "jti123"
+	claims.Subject = "1"
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, claims, nil)
+
+	t.Run("204 No Content - Access Token Only", func(t *testing.T) {
+		mockTokenService.On("Logout", user, claims, "").Return(nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/logout", nil)
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("204 No Content - With Refresh Token", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": "refresh123"}
+		body, _ := json.Marshal(reqBody)
+
+		mockTokenService.On("Logout", user, claims, "refresh123").Return(nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/logout", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Foreign Refresh Token", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": "someone-else"}
+		body, _ := json.Marshal(reqBody)
+
+		mockTokenService.On("Logout", user, claims, "someone-else").Return(services.ErrInvalidRefreshToken).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/logout", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Missing Token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/logout", nil)
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		mockTokenService.On("Logout", user, claims, "").Return(utils.ErrInternal).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/logout", nil)
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
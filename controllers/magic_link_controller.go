@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// magicLinkNonceCookie carries the nonce for browsers; other clients send it
+// back in the request body instead.
+const magicLinkNonceCookie = "magic_link_nonce"
+
+type MagicLinkController struct {
+	magicLinkService services.MagicLinkService
+}
+
+type MagicLinkResp struct {
+	Message string `json:"message"`
+	Nonce   string `json:"nonce"`
+}
+
+func NewMagicLinkController(magicLinkService services.MagicLinkService) *MagicLinkController {
+	return &MagicLinkController{magicLinkService: magicLinkService}
+}
+
+func (c *MagicLinkController) RequestLink(ctx *gin.Context) {
+
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	nonce, err := c.magicLinkService.SendLoginLink(req.Email)
+	if err != nil {
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(magicLinkNonceCookie, nonce, 0, "/v1/login/email/link", "", ctx.Request.TLS != nil, true)
+	utils.RespondJSON(ctx, http.StatusAccepted, MagicLinkResp{
+		Message: "if an account matches, a login link has been sent to it",
+		Nonce:   nonce,
+	})
+}
+
+func (c *MagicLinkController) ConsumeLink(ctx *gin.Context) {
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+		Nonce string `json:"nonce"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	nonce := req.Nonce
+	if nonce == "" {
+		nonce, _ = ctx.Cookie(magicLinkNonceCookie)
+	}
+	if nonce == "" {
+		utils.RespondError(ctx, http.StatusBadRequest, services.ErrInvalidMagicLink.Error())
+		return
+	}
+
+	user, tokens, err := c.magicLinkService.LoginWithLink(req.Token, nonce, clientInfo(ctx))
+	if err != nil {
+		if respondMFARequired(ctx, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidMagicLink) {
+			utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	ctx.SetCookie(magicLinkNonceCookie, "", -1, "/v1/login/email/link", "", ctx.Request.TLS != nil, true)
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, LoginRegisterEmailResp{
+		Email:        userResponse.Email,
+		Phone:        userResponse.Phone,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
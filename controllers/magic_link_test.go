@@ -0,0 +1,99 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMagicLinkLogin(t *testing.T) {
+	mockMagicLinkService := new(services.MagicLinkServiceMock)
+	controller := controllers.NewMagicLinkController(mockMagicLinkService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login/email/link", controller.RequestLink)
+	router.POST("/v1/login/email/link/consume", controller.ConsumeLink)
+
+	doRequest := func(path string, reqBody map[string]string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("202 Accepted - Link Requested And Nonce Cookie Set", func(t *testing.T) {
+		mockMagicLinkService.On("SendLoginLink", "name@name.com").Return("nonce123", nil).Once()
+
+		resp := doRequest("/v1/login/email/link", map[string]string{"email": "name@name.com"})
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		expectedResponse := `{"message":"if an account matches, a login link has been sent to it", "nonce":"nonce123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+
+		cookies := resp.Result().Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, "magic_link_nonce", cookies[0].Name)
+			assert.Equal(t, "nonce123", cookies[0].Value)
+			assert.True(t, cookies[0].HttpOnly)
+		}
+	})
+
+	t.Run("400 Bad Request - Validation Error: Invalid Email", func(t *testing.T) {
+		resp := doRequest("/v1/login/email/link", map[string]string{"email": "name"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("200 OK - Link Consumed With Nonce Cookie", func(t *testing.T) {
+		mockMagicLinkService.On("LoginWithLink", "link123", "nonce123", mock.Anything).
+			Return(&models.User{Email: utils.NewNullableString("name@name.com")}, &services.TokenPair{AccessToken: "token123", RefreshToken: "refresh123"}, nil).Once()
+
+		resp := doRequest("/v1/login/email/link/consume", map[string]string{"token": "link123"},
+			&http.Cookie{Name: "magic_link_nonce", Value: "nonce123"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"email":"name@name.com", "phone":"", "token":"token123", "refresh_token":"refresh123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Missing Nonce", func(t *testing.T) {
+		resp := doRequest("/v1/login/email/link/consume", map[string]string{"token": "link123"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired login link"}`, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Link Used Or Opened In Another Browser", func(t *testing.T) {
+		mockMagicLinkService.On("LoginWithLink", "link123", "othernonce", mock.Anything).
+			Return(nil, nil, services.ErrInvalidMagicLink).Once()
+
+		resp := doRequest("/v1/login/email/link/consume", map[string]string{"token": "link123", "nonce": "othernonce"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired login link"}`, resp.Body.String())
+	})
+
+	t.Run("202 Accepted - MFA Still Required", func(t *testing.T) {
+		mockMagicLinkService.On("LoginWithLink", "link456", "nonce123", mock.Anything).
+			Return(nil, nil, &services.MFARequiredError{MFAToken: "mfa123"}).Once()
+
+		resp := doRequest("/v1/login/email/link/consume", map[string]string{"token": "link456", "nonce": "nonce123"})
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		assert.JSONEq(t, `{"mfa_required":true, "mfa_token":"mfa123"}`, resp.Body.String())
+	})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAController struct {
+	mfaService services.MFAService
+}
+
+type TOTPEnrollmentResp struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func NewMFAController(mfaService services.MFAService) *MFAController {
+	return &MFAController{mfaService: mfaService}
+}
+
+func (c *MFAController) EnrollTOTP(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	secret, uri, err := c.mfaService.EnrollTOTP(user)
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, TOTPEnrollmentResp{
+		Secret:     secret,
+		OTPAuthURI: uri,
+	})
+}
+
+func (c *MFAController) ConfirmTOTP(ctx *gin.Context) {
+
+	var req struct {
+		Code string `json:"code" binding:"required,len=6,numeric"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+
+	codes, err := c.mfaService.ConfirmTOTP(user, req.Code)
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, RecoveryCodesResp{RecoveryCodes: codes})
+}
+
+func (c *MFAController) DisableTOTP(ctx *gin.Context) {
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+
+	if err := c.mfaService.DisableTOTP(user, req.Code); err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *MFAController) LoginWithMFA(ctx *gin.Context) {
+
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, tokens, err := c.mfaService.CompleteLogin(req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		respondMFAError(ctx, err)
+		return
+	}
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, LoginRegisterEmailResp{
+		Email:        userResponse.Email,
+		Phone:        userResponse.Phone,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+func respondMFAError(ctx *gin.Context, err error) {
+	if respondAccountLocked(ctx, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		utils.RespondError(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrMFANotEnrolled):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvalidMFACode),
+		errors.Is(err, services.ErrInvalidMFAToken):
+		utils.RespondError(ctx, http.StatusUnauthorized, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
@@ -0,0 +1,197 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLoginRequiresMFA(t *testing.T) {
+	mockAuthService := new(services.AuthServiceMock)
+	controller := controllers.NewAuthController(mockAuthService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login/email", controller.LoginWithEmail)
+
+	t.Run("202 Accepted - MFA Token Instead Of Access Token", func(t *testing.T) {
+		reqBody := map[string]string{"email": "seller@name.com", "password": "asdfasdf"}
+		body, _ := json.Marshal(reqBody)
+
+		mockAuthService.On("LoginWithEmail", "seller@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, &services.MFARequiredError{MFAToken: "mfa123"})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		expectedResponse := `{"mfa_required":true, "mfa_token":"mfa123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+}
+
+func TestLoginWithMFA(t *testing.T) {
+	mockMFAService := new(services.MFAServiceMock)
+	controller := controllers.NewMFAController(mockMFAService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login/mfa", controller.LoginWithMFA)
+
+	doRequest := func(reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/v1/login/mfa", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Valid Code", func(t *testing.T) {
+		mockMFAService.On("CompleteLogin", "mfa123", "123456", mock.Anything).
+			Return(&models.User{Email: utils.NewNullableString("seller@name.com")}, &services.TokenPair{AccessToken: "token123", RefreshToken: "refresh123"}, nil)
+
+		resp := doRequest(map[string]string{"mfa_token": "mfa123", "code": "123456"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"email":"seller@name.com", "phone":"", "token":"token123", "refresh_token":"refresh123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Validation Error: Required", func(t *testing.T) {
+		resp := doRequest(map[string]string{"mfa_token": "mfa123"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Invalid Code", func(t *testing.T) {
+		mockMFAService.On("CompleteLogin", "mfa123", "000000", mock.Anything).
+			Return(nil, nil, services.ErrInvalidMFACode)
+
+		resp := doRequest(map[string]string{"mfa_token": "mfa123", "code": "000000"})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid verification code"}`, resp.Body.String())
+	})
+
+	t.Run("423 Locked - Too Many Wrong Codes", func(t *testing.T) {
+		mockMFAService.On("CompleteLogin", "mfa123", "111111", mock.Anything).
+			Return(nil, nil, &services.AccountLockedError{RetryAfter: 60 * time.Second})
+
+		resp := doRequest(map[string]string{"mfa_token": "mfa123", "code": "111111"})
+
+		assert.Equal(t, http.StatusLocked, resp.Code)
+		assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+	})
+
+	t.Run("401 Unauthorized - Expired MFA Token", func(t *testing.T) {
+		mockMFAService.On("CompleteLogin", "expired", "123456", mock.Anything).
+			Return(nil, nil, services.ErrInvalidMFAToken)
+
+		resp := doRequest(map[string]string{"mfa_token": "expired", "code": "123456"})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		mockMFAService.On("CompleteLogin", "broken", "123456", mock.Anything).
+			Return(nil, nil, utils.ErrInternal)
+
+		resp := doRequest(map[string]string{"mfa_token": "broken", "code": "123456"})
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
+
+func TestTOTPEnrollment(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockMFAService := new(services.MFAServiceMock)
+	controller := controllers.NewMFAController(mockMFAService)
+
+	router := utils.SetupRouter()
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.POST("/user/mfa/totp", controller.EnrollTOTP)
+	authenticated.POST("/user/mfa/totp/confirm", controller.ConfirmTOTP)
+	authenticated.DELETE("/user/mfa/totp", controller.DisableTOTP)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("seller@name.com")}
+	claims := &utils.Claims{LoginMethod: "email", SessionID: "10"}
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, claims, nil)
+
+	doRequest := func(method, path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		if reqBody != nil {
+			json.NewEncoder(&body).Encode(reqBody)
+		}
+		req := httptest.NewRequest(method, path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Enroll Returns Secret And URI", func(t *testing.T) {
+		mockMFAService.On("EnrollTOTP", user).
+			Return("JBSWY3DPEHPK3PXP", "otpauth://totp/TutupLapak:seller%40name.com?secret=JBSWY3DPEHPK3PXP", nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/user/mfa/totp", nil)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"secret":"JBSWY3DPEHPK3PXP", "otpauth_uri":"otpauth://totp/TutupLapak:seller%40name.com?secret=JBSWY3DPEHPK3PXP"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("409 Conflict - Already Enabled", func(t *testing.T) {
+		mockMFAService.On("EnrollTOTP", user).Return("", "", services.ErrMFAAlreadyEnabled).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/user/mfa/totp", nil)
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("200 OK - Confirm Returns Recovery Codes", func(t *testing.T) {
+		mockMFAService.On("ConfirmTOTP", user, "123456").
+			Return([]string{"aaaa-bbbb-cccc-dddd", "eeee-ffff-gggg-hhhh"}, nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/user/mfa/totp/confirm", map[string]string{"code": "123456"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"recovery_codes":["aaaa-bbbb-cccc-dddd", "eeee-ffff-gggg-hhhh"]}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Confirm Code Must Be Six Digits", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/user/mfa/totp/confirm", map[string]string{"code": "12ab"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Confirm With Wrong Code", func(t *testing.T) {
+		mockMFAService.On("ConfirmTOTP", user, "000000").Return(nil, services.ErrInvalidMFACode).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/user/mfa/totp/confirm", map[string]string{"code": "000000"})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("204 No Content - Disable With Recovery Code", func(t *testing.T) {
+		mockMFAService.On("DisableTOTP", user, "aaaa-bbbb-cccc-dddd").Return(nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/user/mfa/totp", map[string]string{"code": "aaaa-bbbb-cccc-dddd"})
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcVerifierCookie carries the PKCE code verifier for browsers; other
+// clients send it back in the request body instead.
+const oidcVerifierCookie = "oidc_code_verifier"
+
+type OIDCController struct {
+	oidcService services.OIDCService
+}
+
+type OIDCLoginResp struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	CodeVerifier     string `json:"code_verifier"`
+}
+
+func NewOIDCController(oidcService services.OIDCService) *OIDCController {
+	return &OIDCController{oidcService: oidcService}
+}
+
+// BeginLogin returns the provider URL to send the browser to.
+func (c *OIDCController) BeginLogin(ctx *gin.Context) {
+	authorization, err := c.oidcService.BeginLogin(ctx.Param("provider"))
+	if err != nil {
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcVerifierCookie, authorization.CodeVerifier, 0, "/v1/login/oidc", "", ctx.Request.TLS != nil, true)
+	utils.RespondJSON(ctx, http.StatusOK, OIDCLoginResp{
+		AuthorizationURL: authorization.AuthorizationURL,
+		State:            authorization.State,
+		CodeVerifier:     authorization.CodeVerifier,
+	})
+}
+
+// Callback takes the code and state the provider redirected back with and
+// answers with the same body as the password logins.
+func (c *OIDCController) Callback(ctx *gin.Context) {
+
+	var req struct {
+		State        string `json:"state" binding:"required"`
+		Code         string `json:"code" binding:"required"`
+		CodeVerifier string `json:"code_verifier"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	verifier := req.CodeVerifier
+	if verifier == "" {
+		verifier, _ = ctx.Cookie(oidcVerifierCookie)
+	}
+	if verifier == "" {
+		utils.RespondError(ctx, http.StatusBadRequest, services.ErrInvalidOIDCState.Error())
+		return
+	}
+
+	user, tokens, err := c.oidcService.FinishLogin(req.State, req.Code, verifier, clientInfo(ctx))
+	if err != nil {
+		if respondMFARequired(ctx, err) {
+			return
+		}
+		respondOIDCError(ctx, err)
+		return
+	}
+
+	ctx.SetCookie(oidcVerifierCookie, "", -1, "/v1/login/oidc", "", ctx.Request.TLS != nil, true)
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, LoginResp{
+		Email:        userResponse.Email,
+		Phone:        userResponse.Phone,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+func respondOIDCError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownOIDCProvider):
+		utils.RespondError(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidOIDCState):
+		utils.RespondError(ctx, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrOIDCLoginFailed):
+		utils.RespondError(ctx, http.StatusUnauthorized, services.ErrOIDCLoginFailed.Error())
+	case errors.Is(err, services.ErrOIDCEmailNotVerified):
+		utils.RespondError(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrOIDCAccountConflict):
+		utils.RespondError(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrOIDCProviderUnavailable):
+		utils.RespondError(ctx, http.StatusBadGateway, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
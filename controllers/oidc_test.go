@@ -0,0 +1,252 @@
+package controllers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/oidc"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOIDCLogin runs the authorization code flow through the real service
+// against a local fake provider; only storage and tokens are mocked.
+func TestOIDCLogin(t *testing.T) {
+	provider, err := oidc.NewFakeProvider("client123", "secret123")
+	require.NoError(t, err)
+	server := httptest.NewServer(provider)
+	defer server.Close()
+	provider.Issuer = server.URL
+
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	mockIdentityRepo := new(repositories.UserIdentityRepositoryMock)
+	mockVerificationCodeRepo := new(repositories.VerificationCodeRepositoryMock)
+	mockTokenService := new(services.TokenServiceMock)
+
+	cfg := config.Config{
+		OIDCProviders: map[string]config.OIDCProvider{
+			"fake": {Issuer: server.URL, ClientID: "client123", ClientSecret: "secret123"},
+		},
+		OIDCRedirectURL:        "http://localhost:8080/login/oidc/callback",
+		OIDCStateExpiryMinutes: 10,
+	}
+	service := services.NewOIDCService(mockUserRepo, mockIdentityRepo, mockVerificationCodeRepo, mockTokenService, cfg)
+	controller := controllers.NewOIDCController(service)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login/oidc/callback", controller.Callback)
+	router.POST("/v1/login/oidc/:provider", controller.BeginLogin)
+
+	// The state is stored through the verification code repository; the
+	// mock hands it back once when the callback arrives.
+	var states []*models.VerificationCode
+	mockVerificationCodeRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		states = append(states, args.Get(0).(*models.VerificationCode))
+	}).Return(nil)
+
+	doRequest := func(path string, body interface{}, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// begin starts a login and follows the authorization URL the way a
+	// browser would, returning the callback parameters.
+	begin := func(t *testing.T) (controllers.OIDCLoginResp, url.Values, *http.Cookie) {
+		t.Helper()
+		resp := doRequest("/v1/login/oidc/fake", nil)
+		require.Equal(t, http.StatusOK, resp.Code)
+		var started controllers.OIDCLoginResp
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &started))
+
+		stored := states[len(states)-1]
+		assert.Equal(t, services.PurposeOIDCLogin, stored.Purpose)
+		assert.Equal(t, "fake", stored.Target)
+		assert.Equal(t, utils.HashToken(started.State), stored.CodeHash)
+		mockVerificationCodeRepo.On("Consume", services.PurposeOIDCLogin, stored.CodeHash).Return(stored, nil).Once()
+
+		browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		redirect, err := browser.Get(started.AuthorizationURL)
+		require.NoError(t, err)
+		redirect.Body.Close()
+		require.Equal(t, http.StatusFound, redirect.StatusCode)
+		location, err := url.Parse(redirect.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/login/oidc/callback", location.Path)
+
+		return started, location.Query(), resp.Result().Cookies()[0]
+	}
+
+	verifiedAt := sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	tokens := &services.TokenPair{AccessToken: "access123", RefreshToken: "refresh123"}
+	expectedResponse := `{"email":"name@name.com", "phone":"", "token":"access123", "refresh_token":"refresh123"}`
+
+	t.Run("200 OK - First Login Creates Account", func(t *testing.T) {
+		provider.User = oidc.FakeUser{Subject: "sub1", Email: "name@name.com", EmailVerified: true}
+		started, callback, cookie := begin(t)
+		assert.Equal(t, "oidc_code_verifier", cookie.Name)
+		assert.Equal(t, started.CodeVerifier, cookie.Value)
+		assert.True(t, cookie.HttpOnly)
+
+		mockIdentityRepo.On("FindByIssuerSubject", server.URL, "sub1").Return(nil, nil).Once()
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(nil, nil).Once()
+		mockUserRepo.On("CreateUser", mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(0).(*models.User).ID = 1
+		}).Return(nil).Once()
+		mockUserRepo.On("MarkEmailVerified", 1, "name@name.com").Return(true, nil).Once()
+		mockIdentityRepo.On("Create", mock.MatchedBy(func(identity *models.UserIdentity) bool {
+			return identity.UserID == 1 && identity.Issuer == server.URL && identity.Subject == "sub1"
+		})).Return(nil).Once()
+		mockTokenService.On("IssueTokens", mock.Anything, services.LoginMethodOIDC, mock.Anything).Return(tokens, nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state": callback.Get("state"),
+			"code":  callback.Get("code"),
+		}, cookie)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+		mockIdentityRepo.AssertExpectations(t)
+	})
+
+	t.Run("200 OK - Linked Identity", func(t *testing.T) {
+		provider.User = oidc.FakeUser{Subject: "sub1", Email: "other@name.com"}
+		started, callback, _ := begin(t)
+
+		user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com"), EmailVerifiedAt: verifiedAt}
+		mockIdentityRepo.On("FindByIssuerSubject", server.URL, "sub1").Return(&models.UserIdentity{ID: 3, UserID: 1}, nil).Once()
+		mockIdentityRepo.On("RecordLogin", 3).Return(nil).Once()
+		mockUserRepo.On("FindByID", 1).Return(user, nil).Once()
+		mockTokenService.On("IssueTokens", user, services.LoginMethodOIDC, mock.Anything).Return(tokens, nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state":         callback.Get("state"),
+			"code":          callback.Get("code"),
+			"code_verifier": started.CodeVerifier,
+		})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("200 OK - Links Verified Local Account", func(t *testing.T) {
+		provider.User = oidc.FakeUser{Subject: "sub2", Email: "name@name.com", EmailVerified: true}
+		_, callback, cookie := begin(t)
+
+		user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com"), EmailVerifiedAt: verifiedAt}
+		mockIdentityRepo.On("FindByIssuerSubject", server.URL, "sub2").Return(nil, nil).Once()
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(user, nil).Once()
+		mockIdentityRepo.On("Create", mock.MatchedBy(func(identity *models.UserIdentity) bool {
+			return identity.UserID == 1 && identity.Subject == "sub2"
+		})).Return(nil).Once()
+		mockTokenService.On("IssueTokens", user, services.LoginMethodOIDC, mock.Anything).Return(tokens, nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state": callback.Get("state"),
+			"code":  callback.Get("code"),
+		}, cookie)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+	})
+
+	t.Run("202 Accepted - MFA Required", func(t *testing.T) {
+		provider.User = oidc.FakeUser{Subject: "sub1"}
+		_, callback, cookie := begin(t)
+
+		user := &models.User{ID: 1, TOTPEnabledAt: verifiedAt}
+		mockIdentityRepo.On("FindByIssuerSubject", server.URL, "sub1").Return(&models.UserIdentity{ID: 3, UserID: 1}, nil).Once()
+		mockIdentityRepo.On("RecordLogin", 3).Return(nil).Once()
+		mockUserRepo.On("FindByID", 1).Return(user, nil).Once()
+		mockTokenService.On("IssueMFAToken", user, services.LoginMethodOIDC).Return("mfa123", nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state": callback.Get("state"),
+			"code":  callback.Get("code"),
+		}, cookie)
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		assert.Contains(t, resp.Body.String(), "mfa123")
+	})
+
+	t.Run("409 Conflict - Unverified Local Account", func(t *testing.T) {
+		provider.User = oidc.FakeUser{Subject: "sub3", Email: "name@name.com", EmailVerified: true}
+		_, callback, cookie := begin(t)
+
+		mockIdentityRepo.On("FindByIssuerSubject", server.URL, "sub3").Return(nil, nil).Once()
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(&models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}, nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state": callback.Get("state"),
+			"code":  callback.Get("code"),
+		}, cookie)
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("403 Forbidden - Email Not Verified By Provider", func(t *testing.T) {
+		provider.User = oidc.FakeUser{Subject: "sub4", Email: "name@name.com"}
+		_, callback, cookie := begin(t)
+
+		mockIdentityRepo.On("FindByIssuerSubject", server.URL, "sub4").Return(nil, nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state": callback.Get("state"),
+			"code":  callback.Get("code"),
+		}, cookie)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Code Verifier Of Another Login", func(t *testing.T) {
+		_, callback, _ := begin(t)
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state":         callback.Get("state"),
+			"code":          callback.Get("code"),
+			"code_verifier": "verifier123",
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"sign in with the identity provider failed"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Expired Or Used State", func(t *testing.T) {
+		mockVerificationCodeRepo.On("Consume", services.PurposeOIDCLogin, utils.HashToken("state123")).Return(nil, nil).Once()
+
+		resp := doRequest("/v1/login/oidc/callback", map[string]string{
+			"state":         "state123",
+			"code":          "code123",
+			"code_verifier": "verifier123",
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"sign in request expired or was already used, please start again"}`, resp.Body.String())
+	})
+
+	t.Run("404 Not Found - Unknown Provider", func(t *testing.T) {
+		resp := doRequest("/v1/login/oidc/other", nil)
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+}
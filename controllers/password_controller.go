@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordController struct {
+	passwordService services.PasswordService
+}
+
+type MessageResp struct {
+	Message string `json:"message"`
+}
+
+type FieldErrorsResp struct {
+	Error  string              `json:"error"`
+	Fields map[string][]string `json:"fields"`
+}
+
+func NewPasswordController(passwordService services.PasswordService) *PasswordController {
+	return &PasswordController{passwordService: passwordService}
+}
+
+func (c *PasswordController) ForgotPassword(ctx *gin.Context) {
+
+	var req struct {
+		Identifier string `json:"identifier" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	if err := c.passwordService.ForgotPassword(req.Identifier); err != nil {
+		respondPasswordError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusAccepted, MessageResp{
+		Message: "if an account matches, a reset code has been sent to it",
+	})
+}
+
+func (c *PasswordController) ResetPassword(ctx *gin.Context) {
+
+	var req struct {
+		Identifier  string `json:"identifier" binding:"required"`
+		Code        string `json:"code" binding:"required,len=6,numeric"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	if err := c.passwordService.ResetPassword(req.Identifier, req.Code, req.NewPassword); err != nil {
+		if respondPasswordPolicy(ctx, err, "new_password") {
+			return
+		}
+		respondPasswordError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *PasswordController) ChangePassword(ctx *gin.Context) {
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+	claims, _ := middleware.CurrentClaims(ctx)
+
+	tokens, err := c.passwordService.ChangePassword(user, claims.LoginMethod, req.CurrentPassword, req.NewPassword, clientInfo(ctx))
+	if err != nil {
+		if respondPasswordPolicy(ctx, err, "new_password") {
+			return
+		}
+		respondPasswordError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, TokenResp{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+func respondPasswordError(ctx *gin.Context, err error) {
+	if respondAccountLocked(ctx, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, services.ErrWrongPassword):
+		utils.RespondError(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrSamePassword):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvalidIdentifier), errors.Is(err, services.ErrInvalidOTP):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTooManyOTPAttempts):
+		utils.RespondError(ctx, http.StatusTooManyRequests, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
+
+// respondPasswordPolicy answers with every policy rule a new password broke,
+// listed under the request field it came from, and reports whether it did so.
+func respondPasswordPolicy(ctx *gin.Context, err error, field string) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	utils.RespondJSON(ctx, http.StatusBadRequest, FieldErrorsResp{
+		Error:  err.Error(),
+		Fields: map[string][]string{field: policyErr.Violations},
+	})
+	return true
+}
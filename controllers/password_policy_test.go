@@ -0,0 +1,66 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPasswordPolicyErrors(t *testing.T) {
+	mockAuthService := new(services.AuthServiceMock)
+	mockPasswordService := new(services.PasswordServiceMock)
+	authController := controllers.NewAuthController(mockAuthService)
+	passwordController := controllers.NewPasswordController(mockPasswordService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/register/phone", authController.RegisterWithPhone)
+	router.POST("/v1/password/reset", passwordController.ResetPassword)
+
+	doRequest := func(path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	policyErr := &services.PasswordPolicyError{Violations: []string{
+		"must mix at least 2 of lowercase letters, uppercase letters, digits and symbols",
+		"is too easy to guess",
+	}}
+
+	t.Run("400 Bad Request - Register Lists Violations Under password", func(t *testing.T) {
+		mockAuthService.On("RegisterWithPhone", "+6281234567890", "aaaaaaaa", mock.Anything).Return(nil, nil, policyErr).Once()
+
+		resp := doRequest("/v1/register/phone", map[string]string{"phone": "+6281234567890", "password": "aaaaaaaa"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		expectedResponse := `{
+			"error":"password does not meet the requirements",
+			"fields":{"password":["must mix at least 2 of lowercase letters, uppercase letters, digits and symbols", "is too easy to guess"]}
+		}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Reset Lists Violations Under new_password", func(t *testing.T) {
+		mockPasswordService.On("ResetPassword", "name@name.com", "123456", "aaaaaaaa").Return(policyErr).Once()
+
+		resp := doRequest("/v1/password/reset", map[string]string{"identifier": "name@name.com", "code": "123456", "new_password": "aaaaaaaa"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		expectedResponse := `{
+			"error":"password does not meet the requirements",
+			"fields":{"new_password":["must mix at least 2 of lowercase letters, uppercase letters, digits and symbols", "is too easy to guess"]}
+		}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+}
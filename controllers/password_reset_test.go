@@ -0,0 +1,99 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordReset(t *testing.T) {
+	mockPasswordService := new(services.PasswordServiceMock)
+	controller := controllers.NewPasswordController(mockPasswordService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/password/forgot", controller.ForgotPassword)
+	router.POST("/v1/password/reset", controller.ResetPassword)
+
+	doRequest := func(path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("202 Accepted - Same Response For Known And Unknown Identifiers", func(t *testing.T) {
+		mockPasswordService.On("ForgotPassword", "name@name.com").Return(nil).Once()
+		mockPasswordService.On("ForgotPassword", "+6289999999999").Return(nil).Once()
+
+		known := doRequest("/v1/password/forgot", map[string]string{"identifier": "name@name.com"})
+		unknown := doRequest("/v1/password/forgot", map[string]string{"identifier": "+6289999999999"})
+
+		assert.Equal(t, http.StatusAccepted, known.Code)
+		assert.Equal(t, known.Code, unknown.Code)
+		assert.Equal(t, known.Body.String(), unknown.Body.String())
+	})
+
+	t.Run("400 Bad Request - Identifier Is Neither Email Nor Phone", func(t *testing.T) {
+		mockPasswordService.On("ForgotPassword", "name").Return(services.ErrInvalidIdentifier).Once()
+
+		resp := doRequest("/v1/password/forgot", map[string]string{"identifier": "name"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("204 No Content - Password Reset", func(t *testing.T) {
+		mockPasswordService.On("ResetPassword", "name@name.com", "123456", "newpassword").Return(nil).Once()
+
+		resp := doRequest("/v1/password/reset", map[string]string{
+			"identifier":   "name@name.com",
+			"code":         "123456",
+			"new_password": "newpassword",
+		})
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("204 No Content - Long Passphrase Is Left To The Policy", func(t *testing.T) {
+		passphrase := "correct horse battery staple and then some more words"
+		mockPasswordService.On("ResetPassword", "name@name.com", "123456", passphrase).Return(nil).Once()
+
+		resp := doRequest("/v1/password/reset", map[string]string{
+			"identifier":   "name@name.com",
+			"code":         "123456",
+			"new_password": passphrase,
+		})
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Validation Error: Missing New Password", func(t *testing.T) {
+		resp := doRequest("/v1/password/reset", map[string]string{
+			"identifier": "name@name.com",
+			"code":       "123456",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Wrong Code", func(t *testing.T) {
+		mockPasswordService.On("ResetPassword", "name@name.com", "000000", "newpassword").Return(services.ErrInvalidOTP).Once()
+
+		resp := doRequest("/v1/password/reset", map[string]string{
+			"identifier":   "name@name.com",
+			"code":         "000000",
+			"new_password": "newpassword",
+		})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired code"}`, resp.Body.String())
+	})
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PhoneLoginController struct {
+	phoneLoginService services.PhoneLoginService
+}
+
+func NewPhoneLoginController(phoneLoginService services.PhoneLoginService) *PhoneLoginController {
+	return &PhoneLoginController{phoneLoginService: phoneLoginService}
+}
+
+func (c *PhoneLoginController) RequestCode(ctx *gin.Context) {
+
+	var req struct {
+		Phone string `json:"phone" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	if err := c.phoneLoginService.SendLoginCode(req.Phone); err != nil {
+		respondPhoneLoginError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusAccepted, MessageResp{
+		Message: "if the number can sign in, a login code has been sent to it",
+	})
+}
+
+func (c *PhoneLoginController) VerifyCode(ctx *gin.Context) {
+
+	var req struct {
+		Phone string `json:"phone" binding:"required"`
+		Code  string `json:"code" binding:"required,len=6,numeric"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, tokens, err := c.phoneLoginService.LoginWithCode(req.Phone, req.Code, clientInfo(ctx))
+	if err != nil {
+		if respondMFARequired(ctx, err) {
+			return
+		}
+		respondPhoneLoginError(ctx, err)
+		return
+	}
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, LoginRegisterPhoneResp{
+		Phone:        userResponse.Phone,
+		Email:        userResponse.Email,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+func respondPhoneLoginError(ctx *gin.Context, err error) {
+	var cooldownErr *services.OTPCooldownError
+	switch {
+	case errors.As(err, &cooldownErr):
+		respondCooldown(ctx, cooldownErr)
+	case errors.Is(err, services.ErrTooManyOTPAttempts):
+		utils.RespondError(ctx, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrInvalidIdentifier), errors.Is(err, services.ErrInvalidOTP):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
+
+// respondCooldown answers 429 with the time until another code may be sent.
+func respondCooldown(ctx *gin.Context, err *services.OTPCooldownError) {
+	ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	utils.RespondError(ctx, http.StatusTooManyRequests, err.Error())
+}
@@ -0,0 +1,84 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPhoneOTPLogin(t *testing.T) {
+	mockPhoneLoginService := new(services.PhoneLoginServiceMock)
+	controller := controllers.NewPhoneLoginController(mockPhoneLoginService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/login/phone/otp", controller.RequestCode)
+	router.POST("/v1/login/phone/otp/verify", controller.VerifyCode)
+
+	doRequest := func(path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("202 Accepted - Code Requested", func(t *testing.T) {
+		mockPhoneLoginService.On("SendLoginCode", "+6281234567890").Return(nil).Once()
+
+		resp := doRequest("/v1/login/phone/otp", map[string]string{"phone": "+6281234567890"})
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+	})
+
+	t.Run("429 Too Many Requests - Resend Cooldown", func(t *testing.T) {
+		mockPhoneLoginService.On("SendLoginCode", "+6281234567891").
+			Return(&services.OTPCooldownError{RetryAfter: 42500 * time.Millisecond}).Once()
+
+		resp := doRequest("/v1/login/phone/otp", map[string]string{"phone": "+6281234567891"})
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "43", resp.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"please wait before requesting another code"}`, resp.Body.String())
+	})
+
+	t.Run("200 OK - Valid Code", func(t *testing.T) {
+		mockPhoneLoginService.On("LoginWithCode", "+6281234567890", "123456", mock.Anything).
+			Return(&models.User{Phone: utils.NewNullableString("+6281234567890")}, &services.TokenPair{AccessToken: "token123", RefreshToken: "refresh123"}, nil).Once()
+
+		resp := doRequest("/v1/login/phone/otp/verify", map[string]string{"phone": "+6281234567890", "code": "123456"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"phone":"+6281234567890", "email":"", "token":"token123", "refresh_token":"refresh123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Wrong Code", func(t *testing.T) {
+		mockPhoneLoginService.On("LoginWithCode", "+6281234567890", "000000", mock.Anything).
+			Return(nil, nil, services.ErrInvalidOTP).Once()
+
+		resp := doRequest("/v1/login/phone/otp/verify", map[string]string{"phone": "+6281234567890", "code": "000000"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired code"}`, resp.Body.String())
+	})
+
+	t.Run("429 Too Many Requests - Attempts Exhausted", func(t *testing.T) {
+		mockPhoneLoginService.On("LoginWithCode", "+6281234567890", "111111", mock.Anything).
+			Return(nil, nil, services.ErrTooManyOTPAttempts).Once()
+
+		resp := doRequest("/v1/login/phone/otp/verify", map[string]string{"phone": "+6281234567890", "code": "111111"})
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	})
+}
@@ -0,0 +1,104 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRefreshToken(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	controller := controllers.NewTokenController(mockTokenService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/token/refresh", controller.Refresh)
+
+	t.Run("200 OK - Token Rotated", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": "refresh123"}
+		body, _ := json.Marshal(reqBody)
+
+		mockTokenService.On("Refresh", "refresh123", mock.Anything).
+			Return(&services.TokenPair{AccessToken: "token456", RefreshToken: "refresh456"}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"token":"token456", "refresh_token":"refresh456"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("400 Bad Request - Validation Error: Required", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": ""}
+		body, _ := json.Marshal(reqBody)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Invalid Token", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": "unknown"}
+		body, _ := json.Marshal(reqBody)
+
+		mockTokenService.On("Refresh", "unknown", mock.Anything).
+			Return(nil, services.ErrInvalidRefreshToken)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		expectedResponse := `{"error":"invalid refresh token"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Reused Token", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": "rotated"}
+		body, _ := json.Marshal(reqBody)
+
+		mockTokenService.On("Refresh", "rotated", mock.Anything).
+			Return(nil, services.ErrRefreshTokenReused)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		reqBody := map[string]string{"refresh_token": "broken"}
+		body, _ := json.Marshal(reqBody)
+
+		mockTokenService.On("Refresh", "broken", mock.Anything).
+			Return(nil, utils.ErrInternal)
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/token/refresh", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestRegisterWithEmail(t *testing.T) {
@@ -26,8 +27,8 @@ func TestRegisterWithEmail(t *testing.T) {
 		reqBody := map[string]string{"email": "name@name.com", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("RegisterWithEmail", "name@name.com", "asdfasdf").
-			Return(&models.User{Email: utils.NewNullableString("name@name.com")}, "token123", nil)
+		mockAuthServiceMock.On("RegisterWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(&models.User{Email: utils.NewNullableString("name@name.com")}, &services.TokenPair{AccessToken: "token123"}, nil)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/register/email", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -95,8 +96,8 @@ func TestRegisterWithEmail(t *testing.T) {
 		reqBody := map[string]string{"email": "name@name.com", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("RegisterWithEmail", "name@name.com", "asdfasdf").
-			Return(nil, "", errors.New("email already exists")).Once()
+		mockAuthServiceMock.On("RegisterWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, errors.New("email already exists")).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/register/email", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -119,8 +120,8 @@ func TestRegisterWithEmail(t *testing.T) {
 		reqBody := map[string]string{"email": "name@name.com", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("RegisterWithEmail", "name@name.com", "asdfasdf").
-			Return(nil, "", utils.ErrInternal)
+		mockAuthServiceMock.On("RegisterWithEmail", "name@name.com", "asdfasdf", mock.Anything).
+			Return(nil, nil, utils.ErrInternal)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/register/email", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
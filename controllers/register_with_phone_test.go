@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestRegisterWithPhone(t *testing.T) {
@@ -26,8 +27,8 @@ func TestRegisterWithPhone(t *testing.T) {
 		reqBody := map[string]string{"phone": "+548877653745", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("RegisterWithPhone", "+548877653745", "asdfasdf").
-			Return(&models.User{Phone: utils.NewNullableString("+548877653745")}, "token123", nil)
+		mockAuthServiceMock.On("RegisterWithPhone", "+548877653745", "asdfasdf", mock.Anything).
+			Return(&models.User{Phone: utils.NewNullableString("+548877653745")}, &services.TokenPair{AccessToken: "token123"}, nil)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/register/phone", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -95,8 +96,8 @@ func TestRegisterWithPhone(t *testing.T) {
 		reqBody := map[string]string{"phone": "+67675899", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("RegisterWithPhone", "+67675899", "asdfasdf").
-			Return(nil, "", errors.New("phone already exists")).Once()
+		mockAuthServiceMock.On("RegisterWithPhone", "+67675899", "asdfasdf", mock.Anything).
+			Return(nil, nil, errors.New("phone already exists")).Once()
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/register/phone", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -119,8 +120,8 @@ func TestRegisterWithPhone(t *testing.T) {
 		reqBody := map[string]string{"phone": "+67675899", "password": "asdfasdf"}
 		body, _ := json.Marshal(reqBody)
 
-		mockAuthServiceMock.On("RegisterWithPhone", "+67675899", "asdfasdf").
-			Return(nil, "", utils.ErrInternal)
+		mockAuthServiceMock.On("RegisterWithPhone", "+67675899", "asdfasdf", mock.Anything).
+			Return(nil, nil, utils.ErrInternal)
 
 		req := httptest.NewRequest(http.MethodPost, "/v1/register/phone", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleController struct {
+	roleService services.RoleService
+}
+
+type UserRolesResp struct {
+	UserID int      `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+func NewRoleController(roleService services.RoleService) *RoleController {
+	return &RoleController{roleService: roleService}
+}
+
+func (c *RoleController) List(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	roles, err := c.roleService.ListUserRoles(userID)
+	if err != nil {
+		respondRoleError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, UserRolesResp{UserID: userID, Roles: roles})
+}
+
+func (c *RoleController) Grant(ctx *gin.Context) {
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(ctx)
+
+	roles, err := c.roleService.GrantRole(actor, userID, req.Role, clientInfo(ctx))
+	if err != nil {
+		respondRoleError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, UserRolesResp{UserID: userID, Roles: roles})
+}
+
+func (c *RoleController) Revoke(ctx *gin.Context) {
+	userID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	actor, _ := middleware.CurrentUser(ctx)
+
+	roles, err := c.roleService.RevokeRole(actor, userID, ctx.Param("role"), clientInfo(ctx))
+	if err != nil {
+		respondRoleError(ctx, err)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, UserRolesResp{UserID: userID, Roles: roles})
+}
+
+func respondRoleError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrRoleNotGranted):
+		utils.RespondError(ctx, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUnknownRole), errors.Is(err, services.ErrDefaultRole):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrOwnRoles):
+		utils.RespondError(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrRoleAlreadyGranted):
+		utils.RespondError(ctx, http.StatusConflict, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
@@ -0,0 +1,149 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRoles manages roles through the admin API with the real service; only
+// storage, sessions and tokens are mocked.
+func TestRoles(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockSessionService := new(services.SessionServiceMock)
+	mockRoleRepo := new(repositories.RoleRepositoryMock)
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	mockRoleRepo.On("ListRolePermissions").Return(map[string][]string{
+		services.RoleBuyer:  {services.PermissionOrdersCreate},
+		services.RoleSeller: {services.PermissionProductsManage},
+		services.RoleAdmin:  {services.PermissionRolesManage, services.PermissionUsersRead},
+	}, nil)
+	service, err := services.NewRoleService(mockRoleRepo, mockUserRepo, mockSessionService, config.Config{DefaultRoles: []string{services.RoleBuyer}})
+	require.NoError(t, err)
+	controller := controllers.NewRoleController(service)
+
+	router := utils.SetupRouter()
+	admin := router.Group("/v1/admin",
+		middleware.Authenticate(mockTokenService, nil),
+		middleware.RequireUserLogin(),
+		middleware.RequirePermission(service, services.PermissionRolesManage),
+	)
+	admin.GET("/users/:id/roles", controller.List)
+	admin.POST("/users/:id/roles", controller.Grant)
+	admin.DELETE("/users/:id/roles/:role", controller.Revoke)
+
+	adminUser := &models.User{ID: 1}
+	mockTokenService.On("VerifyAccessToken", "admin").Return(adminUser, &utils.Claims{Roles: []string{services.RoleBuyer, services.RoleAdmin}}, nil)
+	mockTokenService.On("VerifyAccessToken", "seller").Return(&models.User{ID: 2}, &utils.Claims{Roles: []string{services.RoleBuyer, services.RoleSeller}}, nil)
+	mockUserRepo.On("FindByID", 2).Return(&models.User{ID: 2}, nil)
+	mockUserRepo.On("FindByID", 99).Return(nil, nil)
+
+	doRequest := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = "203.0.113.7:1234"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// audited matches the entry the grant or revoke is recorded with.
+	audited := func(action, role string) interface{} {
+		return mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.ActorID.Int64 == 1 && entry.TargetUserID.Int64 == 2 &&
+				entry.Action == action && entry.Details == role && entry.IPAddress == "203.0.113.7"
+		})
+	}
+
+	t.Run("200 OK - List Roles", func(t *testing.T) {
+		mockRoleRepo.On("ListUserRoles", 2).Return([]string{services.RoleSeller}, nil).Once()
+
+		resp := doRequest(http.MethodGet, "/v1/admin/users/2/roles", "admin", nil)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"user_id":2, "roles":["buyer", "seller"]}`, resp.Body.String())
+	})
+
+	t.Run("200 OK - Grant Role", func(t *testing.T) {
+		mockRoleRepo.On("GrantRole", 2, services.RoleAdmin, audited(services.AuditRoleGranted, services.RoleAdmin)).Return(true, nil).Once()
+		mockRoleRepo.On("ListUserRoles", 2).Return([]string{services.RoleAdmin, services.RoleSeller}, nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/admin/users/2/roles", "admin", map[string]string{"role": "admin"})
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"user_id":2, "roles":["buyer", "admin", "seller"]}`, resp.Body.String())
+		mockRoleRepo.AssertExpectations(t)
+	})
+
+	t.Run("409 Conflict - Role Already Granted", func(t *testing.T) {
+		mockRoleRepo.On("GrantRole", 2, services.RoleSeller, mock.Anything).Return(false, nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/admin/users/2/roles", "admin", map[string]string{"role": "seller"})
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("200 OK - Revoke Role Ends Sessions", func(t *testing.T) {
+		mockRoleRepo.On("RevokeRole", 2, services.RoleSeller, audited(services.AuditRoleRevoked, services.RoleSeller)).Return(true, nil).Once()
+		mockSessionService.On("RevokeAllSessions", 2).Return(nil).Once()
+		mockRoleRepo.On("ListUserRoles", 2).Return([]string{}, nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/admin/users/2/roles/seller", "admin", nil)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"user_id":2, "roles":["buyer"]}`, resp.Body.String())
+		mockSessionService.AssertExpectations(t)
+	})
+
+	t.Run("404 Not Found - Role Not Granted", func(t *testing.T) {
+		mockRoleRepo.On("RevokeRole", 2, services.RoleAdmin, mock.Anything).Return(false, nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/admin/users/2/roles/admin", "admin", nil)
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Unknown Or Default Role", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/admin/users/2/roles", "admin", map[string]string{"role": "owner"})
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"unknown role"}`, resp.Body.String())
+
+		resp = doRequest(http.MethodDelete, "/v1/admin/users/2/roles/buyer", "admin", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("403 Forbidden - Own Roles", func(t *testing.T) {
+		resp := doRequest(http.MethodDelete, "/v1/admin/users/1/roles/admin", "admin", nil)
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"you cannot change your own roles"}`, resp.Body.String())
+	})
+
+	t.Run("404 Not Found - Unknown User", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/admin/users/99/roles", "admin", map[string]string{"role": "seller"})
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+	})
+
+	t.Run("403 Forbidden - Not An Admin", func(t *testing.T) {
+		resp := doRequest(http.MethodPost, "/v1/admin/users/2/roles", "seller", map[string]string{"role": "admin"})
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"you do not have permission to do this"}`, resp.Body.String())
+	})
+}
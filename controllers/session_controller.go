@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController struct {
+	sessionService services.SessionService
+}
+
+type SessionResp struct {
+	ID         int       `json:"id"`
+	DeviceName string    `json:"device_name"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+func NewSessionController(sessionService services.SessionService) *SessionController {
+	return &SessionController{sessionService: sessionService}
+}
+
+func (c *SessionController) List(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+	currentSessionID := currentSessionID(ctx)
+
+	sessions, err := c.sessionService.ListSessions(user.ID)
+	if err != nil {
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	resp := make([]SessionResp, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, SessionResp{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, resp)
+}
+
+func (c *SessionController) Revoke(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	sessionID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.RespondError(ctx, http.StatusBadRequest, "invalid session id")
+		return
+	}
+
+	if err := c.sessionService.RevokeSession(user.ID, sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			utils.RespondError(ctx, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *SessionController) RevokeOthers(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	if err := c.sessionService.RevokeOtherSessions(user.ID, currentSessionID(ctx)); err != nil {
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func currentSessionID(ctx *gin.Context) int {
+	claims, ok := middleware.CurrentClaims(ctx)
+	if !ok {
+		return 0
+	}
+	sessionID, _ := strconv.Atoi(claims.SessionID)
+	return sessionID
+}
@@ -0,0 +1,97 @@
+package controllers_test
+
+import (
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessions(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockSessionService := new(services.SessionServiceMock)
+	controller := controllers.NewSessionController(mockSessionService)
+
+	router := utils.SetupRouter()
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.GET("/sessions", controller.List)
+	authenticated.DELETE("/sessions/:id", controller.Revoke)
+	authenticated.POST("/sessions/revoke-others", controller.RevokeOthers)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	claims := &utils.Claims{LoginMethod: "email", SessionID: "10"}
+	claims.Subject = "1"
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, claims, nil)
+
+	doRequest := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - List Marks Current Session", func(t *testing.T) {
+		seenAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockSessionService.On("ListSessions", 1).Return([]models.Session{
+			{ID: 10, DeviceName: "Chrome on Android", IPAddress: "10.0.0.1", CreatedAt: seenAt, LastSeenAt: seenAt},
+			{ID: 11, DeviceName: "Safari on iOS", IPAddress: "10.0.0.2", CreatedAt: seenAt, LastSeenAt: seenAt},
+		}, nil).Once()
+
+		resp := doRequest(http.MethodGet, "/v1/sessions")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `[
+			{"id":10, "device_name":"Chrome on Android", "ip_address":"10.0.0.1", "created_at":"2025-01-02T03:04:05Z", "last_seen_at":"2025-01-02T03:04:05Z", "current":true},
+			{"id":11, "device_name":"Safari on iOS", "ip_address":"10.0.0.2", "created_at":"2025-01-02T03:04:05Z", "last_seen_at":"2025-01-02T03:04:05Z", "current":false}
+		]`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("204 No Content - Revoke Session", func(t *testing.T) {
+		mockSessionService.On("RevokeSession", 1, 11).Return(nil).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/sessions/11")
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Invalid Session ID", func(t *testing.T) {
+		resp := doRequest(http.MethodDelete, "/v1/sessions/abc")
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("404 Not Found - Someone Else's Session", func(t *testing.T) {
+		mockSessionService.On("RevokeSession", 1, 99).Return(services.ErrSessionNotFound).Once()
+
+		resp := doRequest(http.MethodDelete, "/v1/sessions/99")
+
+		assert.Equal(t, http.StatusNotFound, resp.Code)
+		assert.JSONEq(t, `{"error":"session not found"}`, resp.Body.String())
+	})
+
+	t.Run("204 No Content - Revoke Other Sessions", func(t *testing.T) {
+		mockSessionService.On("RevokeOtherSessions", 1, 10).Return(nil).Once()
+
+		resp := doRequest(http.MethodPost, "/v1/sessions/revoke-others")
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+		mockSessionService.AssertCalled(t, "RevokeOtherSessions", 1, 10)
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		mockSessionService.On("ListSessions", 1).Return(nil, utils.ErrInternal).Once()
+
+		resp := doRequest(http.MethodGet, "/v1/sessions")
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TokenController struct {
+	tokenService services.TokenService
+}
+
+type TokenResp struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type IntrospectionResp struct {
+	Active      bool               `json:"active"`
+	Subject     string             `json:"sub,omitempty"`
+	Scope       string             `json:"scope,omitempty"`
+	TokenType   string             `json:"token_type,omitempty"`
+	Issuer      string             `json:"iss,omitempty"`
+	Audience    []string           `json:"aud,omitempty"`
+	ExpiresAt   int64              `json:"exp,omitempty"`
+	IssuedAt    int64              `json:"iat,omitempty"`
+	JTI         string             `json:"jti,omitempty"`
+	LoginMethod string             `json:"login_method,omitempty"`
+	Roles       []string           `json:"roles,omitempty"`
+	Actor       *utils.ActorClaims `json:"act,omitempty"`
+	Email       string             `json:"email,omitempty"`
+	Phone       string             `json:"phone,omitempty"`
+}
+
+func NewTokenController(tokenService services.TokenService) *TokenController {
+	return &TokenController{tokenService: tokenService}
+}
+
+func (c *TokenController) Refresh(ctx *gin.Context) {
+
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	tokens, err := c.tokenService.Refresh(req.RefreshToken, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, utils.ErrInternal) {
+			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, TokenResp{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+func (c *TokenController) Logout(ctx *gin.Context) {
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+	claims, _ := middleware.CurrentClaims(ctx)
+
+	if err := c.tokenService.Logout(user, claims, req.RefreshToken); err != nil {
+		if errors.Is(err, utils.ErrInternal) {
+			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+			return
+		}
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *TokenController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	utils.RespondJSON(ctx, http.StatusOK, c.tokenService.PublicKeys())
+}
+
+// Introspect implements RFC 7662 for other TutupLapak services. Any token that
+// fails verification is reported as inactive without saying why.
+func (c *TokenController) Introspect(ctx *gin.Context) {
+
+	var req struct {
+		Token         string `form:"token" json:"token" binding:"required"`
+		TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+	}
+
+	if err := ctx.ShouldBind(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, claims, err := c.tokenService.VerifyAccessToken(req.Token)
+	if err != nil {
+		if errors.Is(err, utils.ErrInternal) {
+			utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+			return
+		}
+		utils.RespondJSON(ctx, http.StatusOK, IntrospectionResp{Active: false})
+		return
+	}
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, IntrospectionResp{
+		Active:      true,
+		Subject:     claims.Subject,
+		Scope:       claims.Scope,
+		TokenType:   "Bearer",
+		Issuer:      claims.Issuer,
+		Audience:    claims.Audience,
+		ExpiresAt:   claims.ExpiresAt.Unix(),
+		IssuedAt:    claims.IssuedAt.Unix(),
+		JTI:         claims.ID,
+		LoginMethod: claims.LoginMethod,
+		Roles:       claims.Roles,
+		Actor:       claims.Actor,
+		Email:       userResponse.Email,
+		Phone:       userResponse.Phone,
+	})
+}
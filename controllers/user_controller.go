@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserController struct{}
+
+type UserProfileResp struct {
+	Email             string `json:"email"`
+	Phone             string `json:"phone"`
+	EmailVerified     bool   `json:"email_verified"`
+	PhoneVerified     bool   `json:"phone_verified"`
+	MFAEnabled        bool   `json:"mfa_enabled"`
+	FileID            string `json:"file_id"`
+	FileURI           string `json:"file_uri"`
+	FileThumbnailURI  string `json:"file_thumbnail_uri"`
+	BankAccountName   string `json:"bank_account_name"`
+	BankAccountHolder string `json:"bank_account_holder"`
+	BankAccountNumber string `json:"bank_account_number"`
+}
+
+func NewUserController() *UserController {
+	return &UserController{}
+}
+
+func (c *UserController) Profile(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	utils.RespondJSON(ctx, http.StatusOK, UserProfileResp{
+		Email:             user.Email.String,
+		Phone:             user.Phone.String,
+		EmailVerified:     user.EmailVerifiedAt.Valid,
+		PhoneVerified:     user.PhoneVerifiedAt.Valid,
+		MFAEnabled:        user.TOTPEnabledAt.Valid,
+		FileID:            user.FileID,
+		FileURI:           user.FileURI,
+		FileThumbnailURI:  user.FileThumbnailURI,
+		BankAccountName:   user.BankAccountName,
+		BankAccountHolder: user.BankAccountHolder,
+		BankAccountNumber: user.BankAccountNumber,
+	})
+}
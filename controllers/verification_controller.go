@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VerificationController struct {
+	verificationService services.VerificationService
+}
+
+func NewVerificationController(verificationService services.VerificationService) *VerificationController {
+	return &VerificationController{verificationService: verificationService}
+}
+
+func (c *VerificationController) VerifyEmail(ctx *gin.Context) {
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	if err := c.verificationService.VerifyEmail(req.Token); err != nil {
+		respondVerificationError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *VerificationController) ResendEmailVerification(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	if err := c.verificationService.SendEmailVerification(user); err != nil {
+		respondVerificationError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (c *VerificationController) RequestPhoneVerification(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	if err := c.verificationService.SendPhoneVerification(user); err != nil {
+		respondVerificationError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (c *VerificationController) ConfirmPhoneVerification(ctx *gin.Context) {
+
+	var req struct {
+		Code string `json:"code" binding:"required,len=6,numeric"`
+	}
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+
+	if err := c.verificationService.VerifyPhone(user, req.Code); err != nil {
+		respondVerificationError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func respondVerificationError(ctx *gin.Context, err error) {
+	var cooldownErr *services.OTPCooldownError
+	switch {
+	case errors.As(err, &cooldownErr):
+		respondCooldown(ctx, cooldownErr)
+	case errors.Is(err, services.ErrInvalidVerificationToken), errors.Is(err, services.ErrInvalidOTP):
+		utils.RespondError(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrTooManyOTPAttempts):
+		utils.RespondError(ctx, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrEmailAlreadyVerified), errors.Is(err, services.ErrNoEmailAddress),
+		errors.Is(err, services.ErrPhoneAlreadyVerified), errors.Is(err, services.ErrNoPhoneNumber):
+		utils.RespondError(ctx, http.StatusConflict, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
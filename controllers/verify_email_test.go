@@ -0,0 +1,219 @@
+package controllers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/mailer"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	mockVerificationService := new(services.VerificationServiceMock)
+	controller := controllers.NewVerificationController(mockVerificationService)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/verify/email", controller.VerifyEmail)
+
+	doRequest := func(reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, "/v1/verify/email", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("204 No Content - Valid Token", func(t *testing.T) {
+		mockVerificationService.On("VerifyEmail", "verify123").Return(nil).Once()
+
+		resp := doRequest(map[string]string{"token": "verify123"})
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Validation Error: Required", func(t *testing.T) {
+		resp := doRequest(map[string]string{})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Invalid Or Used Token", func(t *testing.T) {
+		mockVerificationService.On("VerifyEmail", "used123").Return(services.ErrInvalidVerificationToken).Once()
+
+		resp := doRequest(map[string]string{"token": "used123"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired verification token"}`, resp.Body.String())
+	})
+}
+
+func TestResendEmailVerification(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockVerificationService := new(services.VerificationServiceMock)
+	controller := controllers.NewVerificationController(mockVerificationService)
+
+	router := utils.SetupRouter()
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.POST("/verify/email/resend", controller.ResendEmailVerification)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, &utils.Claims{}, nil)
+
+	doRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/verify/email/resend", nil)
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("202 Accepted - Email Sent", func(t *testing.T) {
+		mockVerificationService.On("SendEmailVerification", user).Return(nil).Once()
+
+		resp := doRequest()
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+	})
+
+	t.Run("409 Conflict - Already Verified", func(t *testing.T) {
+		mockVerificationService.On("SendEmailVerification", user).Return(services.ErrEmailAlreadyVerified).Once()
+
+		resp := doRequest()
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.JSONEq(t, `{"error":"email is already verified"}`, resp.Body.String())
+	})
+}
+
+// TestResendEmailVerificationLimits runs resends through the real service,
+// which reads earlier sends from the verification code repository.
+func TestResendEmailVerificationLimits(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockVerificationCodeRepo := new(repositories.VerificationCodeRepositoryMock)
+	mail := mailer.NewMemoryMailer()
+	cfg := config.Config{
+		JWTIssuer:                    "tutuplapak-user",
+		EmailVerificationExpiryHours: 24,
+		EmailResendCooldownSeconds:   60,
+		EmailMaxSendsPerHour:         3,
+	}
+	service := services.NewVerificationService(nil, mockVerificationCodeRepo, mail, nil, utils.NewHMACKeySet("secret"), cfg)
+	controller := controllers.NewVerificationController(service)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/verify/email/resend", middleware.Authenticate(mockTokenService, nil), controller.ResendEmailVerification)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, &utils.Claims{}, nil)
+
+	doRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/verify/email/resend", nil)
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	sentSince := func(sent ...time.Time) {
+		mockVerificationCodeRepo.On("SentSince", services.PurposeEmailVerification, "name@name.com", mock.Anything).Return(sent, nil).Once()
+	}
+	now := time.Now().UTC()
+
+	t.Run("202 Accepted - Cooldown Passed", func(t *testing.T) {
+		sentSince(now.Add(-2 * time.Minute))
+		mockVerificationCodeRepo.On("Create", mock.Anything).Return(nil).Once()
+
+		resp := doRequest()
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+		assert.Len(t, mail.Messages(), 1)
+	})
+
+	t.Run("429 Too Many Requests - Within Cooldown", func(t *testing.T) {
+		sentSince(now.Add(-20 * time.Second))
+
+		resp := doRequest()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+		assert.Len(t, mail.Messages(), 1)
+	})
+
+	t.Run("429 Too Many Requests - Hourly Cap", func(t *testing.T) {
+		sentSince(now.Add(-50*time.Minute), now.Add(-30*time.Minute), now.Add(-10*time.Minute))
+
+		resp := doRequest()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "600", resp.Header().Get("Retry-After"))
+		assert.Len(t, mail.Messages(), 1)
+	})
+}
+
+func TestProfileEmailVerification(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	controller := controllers.NewUserController()
+
+	router := utils.SetupRouter()
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.GET("/user", controller.Profile)
+	verified := authenticated.Group("", middleware.RequireVerifiedEmail())
+	verified.GET("/verified", func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+
+	unverified := &models.User{ID: 1, Email: utils.NewNullableString("new@name.com")}
+	verifiedUser := &models.User{
+		ID:              2,
+		Email:           utils.NewNullableString("old@name.com"),
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	mockTokenService.On("VerifyAccessToken", "unverified").Return(unverified, &utils.Claims{}, nil)
+	mockTokenService.On("VerifyAccessToken", "verified").Return(verifiedUser, &utils.Claims{}, nil)
+
+	doRequest := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Profile Flags Unverified Email", func(t *testing.T) {
+		resp := doRequest("/v1/user", "unverified")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{
+			"email":"new@name.com", "phone":"", "email_verified":false, "phone_verified":false, "mfa_enabled":false,
+			"file_id":"", "file_uri":"", "file_thumbnail_uri":"",
+			"bank_account_name":"", "bank_account_holder":"", "bank_account_number":""
+		}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+	})
+
+	t.Run("403 Forbidden - Unverified Email On Restricted Route", func(t *testing.T) {
+		resp := doRequest("/v1/verified", "unverified")
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"email address is not verified"}`, resp.Body.String())
+	})
+
+	t.Run("204 No Content - Verified Email On Restricted Route", func(t *testing.T) {
+		resp := doRequest("/v1/verified", "verified")
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+}
@@ -0,0 +1,100 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhoneVerification(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockVerificationService := new(services.VerificationServiceMock)
+	controller := controllers.NewVerificationController(mockVerificationService)
+
+	router := utils.SetupRouter()
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.POST("/verify/phone/request", controller.RequestPhoneVerification)
+	authenticated.POST("/verify/phone/confirm", controller.ConfirmPhoneVerification)
+
+	user := &models.User{ID: 1, Phone: utils.NewNullableString("+6281234567890")}
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, &utils.Claims{}, nil)
+
+	doRequest := func(path string, reqBody map[string]string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("202 Accepted - Code Sent", func(t *testing.T) {
+		mockVerificationService.On("SendPhoneVerification", user).Return(nil).Once()
+
+		resp := doRequest("/v1/verify/phone/request", nil)
+
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+	})
+
+	t.Run("409 Conflict - No Phone Number", func(t *testing.T) {
+		mockVerificationService.On("SendPhoneVerification", user).Return(services.ErrNoPhoneNumber).Once()
+
+		resp := doRequest("/v1/verify/phone/request", nil)
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+		assert.JSONEq(t, `{"error":"account has no phone number"}`, resp.Body.String())
+	})
+
+	t.Run("429 Too Many Requests - Resend Cooldown", func(t *testing.T) {
+		mockVerificationService.On("SendPhoneVerification", user).
+			Return(&services.OTPCooldownError{RetryAfter: 42500 * time.Millisecond}).Once()
+
+		resp := doRequest("/v1/verify/phone/request", nil)
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "43", resp.Header().Get("Retry-After"))
+	})
+
+	t.Run("204 No Content - Valid Code", func(t *testing.T) {
+		mockVerificationService.On("VerifyPhone", user, "123456").Return(nil).Once()
+
+		resp := doRequest("/v1/verify/phone/confirm", map[string]string{"code": "123456"})
+
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Validation Error: Not Numeric", func(t *testing.T) {
+		resp := doRequest("/v1/verify/phone/confirm", map[string]string{"code": "12345a"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Wrong Code", func(t *testing.T) {
+		mockVerificationService.On("VerifyPhone", user, "000000").Return(services.ErrInvalidOTP).Once()
+
+		resp := doRequest("/v1/verify/phone/confirm", map[string]string{"code": "000000"})
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired code"}`, resp.Body.String())
+	})
+
+	t.Run("429 Too Many Requests - Attempts Exhausted", func(t *testing.T) {
+		mockVerificationService.On("VerifyPhone", user, "111111").Return(services.ErrTooManyOTPAttempts).Once()
+
+		resp := doRequest("/v1/verify/phone/confirm", map[string]string{"code": "111111"})
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.JSONEq(t, `{"error":"too many attempts, request a new code"}`, resp.Body.String())
+	})
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"errors"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"go-tutuplapak-user/webauthn"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebAuthnController struct {
+	webAuthnService services.WebAuthnService
+}
+
+type WebAuthnCredentialResp struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewWebAuthnController(webAuthnService services.WebAuthnService) *WebAuthnController {
+	return &WebAuthnController{webAuthnService: webAuthnService}
+}
+
+// BeginRegistration returns the options for navigator.credentials.create().
+func (c *WebAuthnController) BeginRegistration(ctx *gin.Context) {
+	user, _ := middleware.CurrentUser(ctx)
+
+	options, err := c.webAuthnService.BeginRegistration(user)
+	if err != nil {
+		respondWebAuthnError(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, options)
+}
+
+// FinishRegistration takes the credential returned by the browser, in the
+// JSON form of PublicKeyCredential.
+func (c *WebAuthnController) FinishRegistration(ctx *gin.Context) {
+
+	var req webauthn.CredentialCreation
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, _ := middleware.CurrentUser(ctx)
+
+	credential, err := c.webAuthnService.FinishRegistration(user, &req)
+	if err != nil {
+		respondWebAuthnError(ctx, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusCreated, WebAuthnCredentialResp{
+		ID:        credential.ID,
+		CreatedAt: credential.CreatedAt,
+	})
+}
+
+// BeginLogin returns the options for navigator.credentials.get().
+func (c *WebAuthnController) BeginLogin(ctx *gin.Context) {
+	options, err := c.webAuthnService.BeginLogin()
+	if err != nil {
+		respondWebAuthnError(ctx, err, http.StatusUnauthorized)
+		return
+	}
+
+	utils.RespondJSON(ctx, http.StatusOK, options)
+}
+
+// FinishLogin answers with the same body as the password logins.
+func (c *WebAuthnController) FinishLogin(ctx *gin.Context) {
+
+	var req webauthn.CredentialAssertion
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.RespondValidationError(ctx, err)
+		return
+	}
+
+	user, tokens, err := c.webAuthnService.FinishLogin(&req, clientInfo(ctx))
+	if err != nil {
+		respondWebAuthnError(ctx, err, http.StatusUnauthorized)
+		return
+	}
+
+	userResponse := utils.ToUserResponse(user)
+
+	utils.RespondJSON(ctx, http.StatusOK, LoginResp{
+		Email:        userResponse.Email,
+		Phone:        userResponse.Phone,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	})
+}
+
+// respondWebAuthnError answers a rejected ceremony with invalidStatus, which
+// is 401 for logins and 400 for registrations.
+func respondWebAuthnError(ctx *gin.Context, err error, invalidStatus int) {
+	switch {
+	case errors.Is(err, services.ErrPasskeyAlreadyRegistered):
+		utils.RespondError(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrWebAuthnChallenge), errors.Is(err, services.ErrInvalidPasskey):
+		utils.RespondError(ctx, invalidStatus, err.Error())
+	default:
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+	}
+}
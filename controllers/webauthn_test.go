@@ -0,0 +1,181 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/controllers"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"go-tutuplapak-user/webauthn"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWebAuthn runs both ceremonies through the real service with a software
+// authenticator in place of the browser; only storage is mocked.
+func TestWebAuthn(t *testing.T) {
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	mockCredentialRepo := new(repositories.WebAuthnCredentialRepositoryMock)
+	mockVerificationCodeRepo := new(repositories.VerificationCodeRepositoryMock)
+	mockTokenService := new(services.TokenServiceMock)
+
+	cfg := config.Config{
+		WebAuthnRPID:           "localhost",
+		WebAuthnRPName:         "TutupLapak",
+		WebAuthnOrigins:        []string{"http://localhost:8080"},
+		WebAuthnTimeoutSeconds: 300,
+	}
+	service := services.NewWebAuthnService(mockUserRepo, mockCredentialRepo, mockVerificationCodeRepo, mockTokenService, cfg)
+	controller := controllers.NewWebAuthnController(service)
+
+	router := utils.SetupRouter()
+	router.POST("/v1/webauthn/login/begin", controller.BeginLogin)
+	router.POST("/v1/webauthn/login/finish", controller.FinishLogin)
+	authenticated := router.Group("/v1", middleware.Authenticate(mockTokenService, nil))
+	authenticated.POST("/webauthn/register/begin", controller.BeginRegistration)
+	authenticated.POST("/webauthn/register/finish", controller.FinishRegistration)
+
+	user := &models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}
+	claims := &utils.Claims{LoginMethod: "email"}
+	claims.Subject = "1"
+	mockTokenService.On("VerifyAccessToken", "token123").Return(user, claims, nil)
+	mockUserRepo.On("FindByID", 1).Return(user, nil)
+
+	// Challenges are stored through the verification code repository; the
+	// mock hands each one back once when the response arrives.
+	var challenges []*models.VerificationCode
+	mockVerificationCodeRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+		challenges = append(challenges, args.Get(0).(*models.VerificationCode))
+	}).Return(nil)
+	expectChallenge := func() *models.VerificationCode {
+		code := challenges[len(challenges)-1]
+		mockVerificationCodeRepo.On("Consume", code.Purpose, code.CodeHash).Return(code, nil).Once()
+		return code
+	}
+
+	doRequest := func(path string, body interface{}, out interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer token123")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		if out != nil {
+			json.Unmarshal(resp.Body.Bytes(), out)
+		}
+		return resp
+	}
+
+	authenticator, err := webauthn.NewSoftwareAuthenticator("http://localhost:8080")
+	require.NoError(t, err)
+
+	var stored *models.WebAuthnCredential
+
+	t.Run("201 Created - Register Passkey", func(t *testing.T) {
+		mockCredentialRepo.On("ListByUser", 1).Return([]models.WebAuthnCredential{}, nil).Once()
+
+		var options webauthn.CreationOptions
+		resp := doRequest("/v1/webauthn/register/begin", nil, &options)
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "localhost", options.RP.ID)
+		assert.Equal(t, []byte("1"), []byte(options.User.ID))
+		assert.Equal(t, services.PurposeWebAuthnRegister, expectChallenge().Purpose)
+
+		credential, err := authenticator.Create(options)
+		require.NoError(t, err)
+
+		mockCredentialRepo.On("FindByCredentialID", authenticator.CredentialID()).Return(nil, nil).Once()
+		mockCredentialRepo.On("Create", mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(0).(*models.WebAuthnCredential)
+			stored.ID = 7
+		}).Return(nil).Once()
+
+		resp = doRequest("/v1/webauthn/register/finish", credential, nil)
+
+		assert.Equal(t, http.StatusCreated, resp.Code)
+		require.NotNil(t, stored)
+		assert.Equal(t, 1, stored.UserID)
+		assert.Equal(t, authenticator.CredentialID(), stored.CredentialID)
+		assert.Equal(t, "internal", stored.Transports)
+	})
+
+	login := func(t *testing.T) (*webauthn.CredentialAssertion, *httptest.ResponseRecorder) {
+		var options webauthn.RequestOptions
+		resp := doRequest("/v1/webauthn/login/begin", nil, &options)
+		require.Equal(t, http.StatusOK, resp.Code)
+		expectChallenge()
+
+		assertion, err := authenticator.Get(options)
+		require.NoError(t, err)
+		return assertion, doRequest("/v1/webauthn/login/finish", assertion, nil)
+	}
+
+	t.Run("200 OK - Login With Passkey", func(t *testing.T) {
+		mockCredentialRepo.On("FindByCredentialID", stored.CredentialID).Return(stored, nil)
+		mockCredentialRepo.On("UpdateSignCount", 7, uint32(1)).Return(true, nil).Once()
+		mockTokenService.On("IssueTokens", user, services.LoginMethodWebAuthn, mock.Anything).
+			Return(&services.TokenPair{AccessToken: "access123", RefreshToken: "refresh123"}, nil).Once()
+
+		_, resp := login(t)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		expectedResponse := `{"email":"name@name.com", "phone":"", "token":"access123", "refresh_token":"refresh123"}`
+		assert.JSONEq(t, expectedResponse, resp.Body.String())
+		stored.SignCount = 1
+	})
+
+	t.Run("401 Unauthorized - Replayed Assertion", func(t *testing.T) {
+		mockCredentialRepo.On("UpdateSignCount", 7, uint32(2)).Return(true, nil).Once()
+		mockTokenService.On("IssueTokens", user, services.LoginMethodWebAuthn, mock.Anything).
+			Return(&services.TokenPair{AccessToken: "access456"}, nil).Once()
+		assertion, resp := login(t)
+		require.Equal(t, http.StatusOK, resp.Code)
+		stored.SignCount = 2
+
+		code := challenges[len(challenges)-1]
+		mockVerificationCodeRepo.On("Consume", code.Purpose, code.CodeHash).Return(nil, nil).Once()
+		resp = doRequest("/v1/webauthn/login/finish", assertion, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"passkey request expired or was already used, please start again"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Cloned Authenticator", func(t *testing.T) {
+		authenticator.SignCount = 0
+
+		_, resp := login(t)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.Contains(t, resp.Body.String(), "may be cloned")
+	})
+
+	t.Run("409 Conflict - Passkey Already Registered", func(t *testing.T) {
+		mockCredentialRepo.On("ListByUser", 1).Return([]models.WebAuthnCredential{}, nil).Once()
+
+		var options webauthn.CreationOptions
+		doRequest("/v1/webauthn/register/begin", nil, &options)
+		expectChallenge()
+		credential, err := authenticator.Create(options)
+		require.NoError(t, err)
+		mockCredentialRepo.On("FindByCredentialID", authenticator.CredentialID()).Return(stored, nil).Once()
+
+		resp := doRequest("/v1/webauthn/register/finish", credential, nil)
+
+		assert.Equal(t, http.StatusConflict, resp.Code)
+	})
+
+	t.Run("400 Bad Request - Malformed Credential", func(t *testing.T) {
+		resp := doRequest("/v1/webauthn/register/finish", map[string]string{"rawId": "not base64!"}, nil)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
@@ -0,0 +1,14 @@
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Owner of the token
+    family_id VARCHAR(64) NOT NULL,                 -- Shared by every token rotated from the same login
+    token_hash VARCHAR(64) NOT NULL,                -- SHA-256 of the opaque token, the token itself is never stored
+    expires_at TIMESTAMP NOT NULL,                  -- Absolute expiry of this token
+    rotated_at TIMESTAMP DEFAULT NULL,              -- Set once the token has been exchanged for a new one
+    revoked_at TIMESTAMP DEFAULT NULL,              -- Set when the token's family is revoked
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Timestamp of issuance
+);
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
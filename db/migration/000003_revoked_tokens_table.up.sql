@@ -0,0 +1,8 @@
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,                    -- ID of the revoked access token
+    expires_at TIMESTAMP NOT NULL,                  -- Expiry of the token, the row is useless afterwards
+    revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Timestamp of revocation
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+CREATE INDEX idx_revoked_tokens_revoked_at ON revoked_tokens (revoked_at);
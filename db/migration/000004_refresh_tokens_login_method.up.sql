@@ -0,0 +1 @@
+ALTER TABLE refresh_tokens ADD COLUMN login_method VARCHAR(20) NOT NULL DEFAULT ''; -- How the token family was originally obtained
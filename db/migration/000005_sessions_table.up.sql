@@ -0,0 +1,15 @@
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,                            -- Auto-incrementing unique identifier, carried in tokens as "sid"
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Owner of the session
+    device_name VARCHAR(255) DEFAULT '',              -- Human readable device derived from the User-Agent
+    user_agent TEXT DEFAULT '',                       -- Raw User-Agent of the login request
+    ip_address VARCHAR(45) DEFAULT '',                -- Client IP of the login request
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,   -- Timestamp of login
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of the latest authenticated request
+    revoked_at TIMESTAMP DEFAULT NULL                 -- Set when the session is logged out or revoked
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+ALTER TABLE refresh_tokens ADD COLUMN session_id INTEGER REFERENCES sessions (id) ON DELETE CASCADE; -- Session the token family belongs to
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens (session_id);
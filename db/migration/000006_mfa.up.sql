@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64) DEFAULT NULL;      -- Base32 TOTP secret, pending until totp_enabled_at is set
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP DEFAULT NULL;    -- Set once enrollment is confirmed with a valid code
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;  -- Last accepted TOTP time step, prevents code replay
+
+CREATE TABLE mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Owner of the code
+    code_hash VARCHAR(64) NOT NULL,                 -- SHA-256 of the normalized recovery code
+    used_at TIMESTAMP DEFAULT NULL,                 -- Set when the code is redeemed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Timestamp of generation
+);
+
+CREATE UNIQUE INDEX idx_mfa_recovery_codes_user_code ON mfa_recovery_codes (user_id, code_hash);
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP DEFAULT NULL; -- Set once the user proves ownership of the email address
+
+CREATE TABLE verification_codes (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    user_id INTEGER REFERENCES users (id) ON DELETE CASCADE, -- User the code was issued for, if any
+    purpose VARCHAR(32) NOT NULL,                   -- What the code proves, e.g. email_verification
+    target VARCHAR(255) NOT NULL,                   -- Email address or phone number the code was sent to
+    code_hash VARCHAR(64) NOT NULL,                 -- SHA-256 of the code or token ID, the secret itself is never stored
+    attempts INTEGER NOT NULL DEFAULT 0,            -- Failed guesses against this code
+    expires_at TIMESTAMP NOT NULL,                  -- Absolute expiry of the code
+    consumed_at TIMESTAMP DEFAULT NULL,             -- Set when the code is used, codes are single use
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Timestamp of issuance
+);
+
+CREATE UNIQUE INDEX idx_verification_codes_purpose_hash ON verification_codes (purpose, code_hash);
+CREATE INDEX idx_verification_codes_purpose_target ON verification_codes (purpose, target);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_verification_codes_purpose_hash;
+CREATE UNIQUE INDEX idx_verification_codes_purpose_hash ON verification_codes (purpose, code_hash);
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at
\ No newline at end of file
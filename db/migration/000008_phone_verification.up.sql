@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN phone_verified_at TIMESTAMP DEFAULT NULL; -- Set once the user proves ownership of the phone number
+
+-- Short numeric codes collide across users, so the hash can no longer be unique.
+DROP INDEX idx_verification_codes_purpose_hash;
+CREATE INDEX idx_verification_codes_purpose_hash ON verification_codes (purpose, code_hash);
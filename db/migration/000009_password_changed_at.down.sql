@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at
\ No newline at end of file
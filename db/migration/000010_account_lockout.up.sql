@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0; -- Consecutive wrong passwords since the last successful login
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP DEFAULT NULL; -- Password logins are refused until this moment
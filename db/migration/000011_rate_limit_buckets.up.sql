@@ -0,0 +1,7 @@
+CREATE TABLE rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,       -- Route, dimension and value, e.g. login:ip:10.0.0.1
+    tokens DOUBLE PRECISION NOT NULL,   -- Requests left in the bucket as of updated_at
+    updated_at TIMESTAMP NOT NULL       -- Last time the bucket was refilled and drawn from
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
@@ -0,0 +1,14 @@
+CREATE TABLE webauthn_credentials (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Owner of the passkey
+    credential_id BYTEA NOT NULL,                   -- Credential ID chosen by the authenticator
+    public_key BYTEA NOT NULL,                      -- COSE_Key as sent at registration
+    sign_count BIGINT NOT NULL DEFAULT 0,           -- Last signature counter seen, detects cloned authenticators
+    aaguid BYTEA DEFAULT NULL,                      -- Authenticator model identifier, zero for "none" attestation
+    transports VARCHAR(255) DEFAULT '',             -- Comma separated transports reported by the browser
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of registration
+    last_used_at TIMESTAMP DEFAULT NULL             -- Timestamp of the latest login with the passkey
+);
+
+CREATE UNIQUE INDEX idx_webauthn_credentials_credential_id ON webauthn_credentials (credential_id);
+CREATE INDEX idx_webauthn_credentials_user_id ON webauthn_credentials (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Local account the identity signs in to
+    issuer VARCHAR(255) NOT NULL,                   -- Issuer URL of the identity provider
+    subject VARCHAR(255) NOT NULL,                  -- Stable user identifier at the provider (sub claim)
+    email VARCHAR(255) DEFAULT NULL,                -- Verified email the provider reported when linking
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of linking
+    last_login_at TIMESTAMP DEFAULT NULL            -- Timestamp of the latest login through the provider
+);
+
+CREATE UNIQUE INDEX idx_user_identities_issuer_subject ON user_identities (issuer, subject);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
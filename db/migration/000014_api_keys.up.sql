@@ -0,0 +1,15 @@
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Account the key acts as
+    name VARCHAR(100) NOT NULL,                     -- Label chosen by the user, e.g. the job using the key
+    prefix VARCHAR(32) NOT NULL,                    -- Public part of the key, used to look it up
+    secret_hash VARCHAR(64) NOT NULL,               -- SHA-256 of the secret part, the key itself is never stored
+    scopes VARCHAR(255) NOT NULL DEFAULT '',        -- Space separated scopes the key was granted
+    expires_at TIMESTAMP DEFAULT NULL,              -- Key stops working after this time, NULL for never
+    last_used_at TIMESTAMP DEFAULT NULL,            -- Timestamp of the latest request made with the key
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- Timestamp of creation
+    revoked_at TIMESTAMP DEFAULT NULL               -- Timestamp the user revoked the key
+);
+
+CREATE UNIQUE INDEX idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX idx_api_keys_user_id ON api_keys (user_id);
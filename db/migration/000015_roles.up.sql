@@ -0,0 +1,58 @@
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    name VARCHAR(50) NOT NULL UNIQUE,               -- Name used in tokens and the admin API
+    description VARCHAR(255) DEFAULT ''             -- What the role is for
+);
+
+CREATE TABLE permissions (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    name VARCHAR(100) NOT NULL UNIQUE,              -- Name checked by RequirePermission, e.g. roles:manage
+    description VARCHAR(255) DEFAULT ''             -- What the permission allows
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,             -- Role granting the permission
+    permission_id INTEGER NOT NULL REFERENCES permissions (id) ON DELETE CASCADE, -- Permission granted
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE user_roles (
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE, -- Account holding the role
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE, -- Role held
+    granted_by INTEGER REFERENCES users (id) ON DELETE SET NULL,      -- Admin who granted it
+    granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,                   -- Timestamp of the grant
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE TABLE audit_log (
+    id SERIAL PRIMARY KEY,                          -- Auto-incrementing unique identifier
+    actor_id INTEGER DEFAULT NULL,                  -- User who acted, kept after the account is deleted
+    action VARCHAR(50) NOT NULL,                    -- What happened, e.g. role.grant
+    target_user_id INTEGER DEFAULT NULL,            -- User the action was applied to
+    details VARCHAR(255) DEFAULT '',                -- Action specific detail, e.g. the role name
+    ip_address VARCHAR(45) DEFAULT '',              -- Address the request came from
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- Timestamp of the action
+);
+
+CREATE INDEX idx_audit_log_target_user_id ON audit_log (target_user_id);
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id);
+
+INSERT INTO roles (name, description) VALUES
+    ('buyer', 'Buys products, held by every account'),
+    ('seller', 'Lists and manages products'),
+    ('admin', 'Manages users and their roles');
+
+INSERT INTO permissions (name, description) VALUES
+    ('orders:create', 'Place orders'),
+    ('products:manage', 'Create, update and delete own products'),
+    ('users:read', 'View other users and their roles'),
+    ('roles:manage', 'Grant and revoke roles');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id FROM roles, permissions
+WHERE (roles.name, permissions.name) IN (
+    ('buyer', 'orders:create'),
+    ('seller', 'products:manage'),
+    ('admin', 'users:read'),
+    ('admin', 'roles:manage')
+);
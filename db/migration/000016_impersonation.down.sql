@@ -0,0 +1 @@
+DELETE FROM permissions WHERE name = 'users:impersonate'
\ No newline at end of file
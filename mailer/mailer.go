@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"fmt"
+	"go-tutuplapak-user/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain text transactional email.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New builds the mailer selected by MAILER_DRIVER: "smtp" for real delivery,
+// "file" to drop messages into a local directory during development, or
+// "memory" to keep them in process for tests.
+func New(cfg config.Config) (Mailer, error) {
+	switch cfg.MailerDriver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "file", "":
+		return NewFileMailer(cfg.MailFileDir, cfg.MailFrom)
+	case "memory":
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", cfg.MailerDriver)
+	}
+}
@@ -0,0 +1,49 @@
+package mailer_test
+
+import (
+	"go-tutuplapak-user/mailer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := mailer.NewFileMailer(dir, "no-reply@tutuplapak.com")
+	require.NoError(t, err)
+
+	err = m.Send(mailer.Message{To: "name@name.com", Subject: "Hello", Body: "line one\nline two"})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), "-name@name.com.eml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "From: no-reply@tutuplapak.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nline one\r\nline two\r\n")
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := mailer.NewMemoryMailer()
+
+	require.NoError(t, m.Send(mailer.Message{To: "a@name.com", Subject: "first"}))
+	require.NoError(t, m.Send(mailer.Message{To: "b@name.com", Subject: "second"}))
+	require.NoError(t, m.Send(mailer.Message{To: "a@name.com", Subject: "third"}))
+
+	assert.Len(t, m.Messages(), 3)
+
+	last, ok := m.Last("a@name.com")
+	assert.True(t, ok)
+	assert.Equal(t, "third", last.Subject)
+
+	_, ok = m.Last("c@name.com")
+	assert.False(t, ok)
+}
@@ -4,10 +4,16 @@ import (
 	"go-tutuplapak-user/config"
 	"go-tutuplapak-user/controllers"
 	"go-tutuplapak-user/db"
+	"go-tutuplapak-user/mailer"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/ratelimit"
 	"go-tutuplapak-user/repositories"
 	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/sms"
+	"go-tutuplapak-user/utils"
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -21,17 +27,185 @@ func main() {
 	}
 
 	userRepo := repositories.NewUserRepository(dbConn)
-	authService := services.NewAuthService(userRepo, cfg)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(dbConn)
+	revokedTokenRepo := repositories.NewRevokedTokenRepository(dbConn)
+	sessionRepo := repositories.NewSessionRepository(dbConn)
+	recoveryCodeRepo := repositories.NewRecoveryCodeRepository(dbConn)
+	verificationCodeRepo := repositories.NewVerificationCodeRepository(dbConn)
+	webAuthnCredentialRepo := repositories.NewWebAuthnCredentialRepository(dbConn)
+	userIdentityRepo := repositories.NewUserIdentityRepository(dbConn)
+	apiKeyRepo := repositories.NewAPIKeyRepository(dbConn)
+	roleRepo := repositories.NewRoleRepository(dbConn)
+	auditLogRepo := repositories.NewAuditLogRepository(dbConn)
+
+	revocationService := services.NewRevocationService(revokedTokenRepo)
+	revocationService.Start(time.Duration(cfg.RevocationSyncSeconds) * time.Second)
+
+	keys, err := utils.LoadKeySet(cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles, cfg.JWTSecret, cfg.JWTAcceptHS256Until)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up mailer: %v", err)
+	}
+
+	smsSender, err := sms.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up SMS sender: %v", err)
+	}
+
+	hasher, err := utils.NewPasswordHasher(cfg.PasswordHashAlgorithm, utils.Argon2idHasher{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	}, cfg.BcryptCost)
+	if err != nil {
+		log.Fatalf("Failed to set up password hashing: %v", err)
+	}
+	utils.SetPasswordHasher(hasher)
+
+	passwordPolicy := &utils.PasswordPolicy{
+		MinLength:      cfg.PasswordMinLength,
+		MaxLength:      cfg.PasswordMaxLength,
+		MinCharClasses: cfg.PasswordMinCharClasses,
+		MinEntropyBits: float64(cfg.PasswordMinEntropyBits),
+	}
+	if cfg.BreachedPasswordsFile != "" {
+		passwordPolicy.Breached, err = utils.LoadBreachedPasswords(cfg.BreachedPasswordsFile)
+		if err != nil {
+			log.Fatalf("Failed to load breached passwords: %v", err)
+		}
+	}
+
+	sessionService := services.NewSessionService(sessionRepo)
+	roleService, err := services.NewRoleService(roleRepo, userRepo, sessionService, cfg)
+	if err != nil {
+		log.Fatalf("Failed to load roles: %v", err)
+	}
+	tokenService := services.NewTokenService(userRepo, refreshTokenRepo, sessionService, revocationService, roleService, keys, cfg)
+	verificationService := services.NewVerificationService(userRepo, verificationCodeRepo, mail, smsSender, keys, cfg)
+	authService := services.NewAuthService(userRepo, tokenService, verificationService, passwordPolicy, cfg)
+	magicLinkService := services.NewMagicLinkService(userRepo, verificationCodeRepo, tokenService, mail, keys, cfg)
+	phoneLoginService := services.NewPhoneLoginService(userRepo, verificationCodeRepo, tokenService, smsSender, cfg)
+	passwordService := services.NewPasswordService(userRepo, verificationCodeRepo, sessionService, tokenService, mail, smsSender, passwordPolicy, cfg)
+	mfaService := services.NewMFAService(userRepo, recoveryCodeRepo, tokenService, cfg)
+	webAuthnService := services.NewWebAuthnService(userRepo, webAuthnCredentialRepo, verificationCodeRepo, tokenService, cfg)
+	oidcService := services.NewOIDCService(userRepo, userIdentityRepo, verificationCodeRepo, tokenService, cfg)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, cfg)
+	impersonationService := services.NewImpersonationService(userRepo, auditLogRepo, tokenService, roleService)
+
 	authController := controllers.NewAuthController(authService)
+	tokenController := controllers.NewTokenController(tokenService)
+	sessionController := controllers.NewSessionController(sessionService)
+	mfaController := controllers.NewMFAController(mfaService)
+	verificationController := controllers.NewVerificationController(verificationService)
+	userController := controllers.NewUserController()
+	passwordController := controllers.NewPasswordController(passwordService)
+	magicLinkController := controllers.NewMagicLinkController(magicLinkService)
+	phoneLoginController := controllers.NewPhoneLoginController(phoneLoginService)
+	adminController := controllers.NewAdminController(authService)
+	webAuthnController := controllers.NewWebAuthnController(webAuthnService)
+	oidcController := controllers.NewOIDCController(oidcService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	roleController := controllers.NewRoleController(roleService)
+	impersonationController := controllers.NewImpersonationController(impersonationService)
+
+	limiter, err := ratelimit.New(cfg, dbConn)
+	if err != nil {
+		log.Fatalf("Failed to set up rate limiter: %v", err)
+	}
+	limitsByIP, maxIPPeriod := parseRateLimits(cfg.RateLimitsByIP)
+	limitsByIdentifier, maxIdentifierPeriod := parseRateLimits(cfg.RateLimitsByIdentifier)
+	if pgLimiter, ok := limiter.(*ratelimit.PostgresLimiter); ok {
+		pgLimiter.Start(time.Hour, max(maxIPPeriod, maxIdentifierPeriod))
+	}
+	rateLimit := func(route string) gin.HandlerFunc {
+		return middleware.RateLimit(limiter, route, limitsByIP[route], limitsByIdentifier[route])
+	}
 
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
+	router.GET("/.well-known/jwks.json", tokenController.JWKS)
 
 	authRoutes := router.Group("/v1")
 	{
-		authRoutes.POST("/login/email", authController.LoginWithEmail)
-		authRoutes.POST("/login/phone", authController.LoginWithPhone)
-		authRoutes.POST("/register/email", authController.RegisterWithEmail)
-		authRoutes.POST("/register/phone", authController.RegisterWithPhone)
+		authRoutes.POST("/login", rateLimit("login"), authController.Login)
+		authRoutes.POST("/login/email", rateLimit("login"), authController.LoginWithEmail)
+		authRoutes.POST("/login/phone", rateLimit("login"), authController.LoginWithPhone)
+		authRoutes.POST("/login/email/link", rateLimit("code"), magicLinkController.RequestLink)
+		authRoutes.POST("/login/email/link/consume", rateLimit("login"), magicLinkController.ConsumeLink)
+		authRoutes.POST("/login/phone/otp", rateLimit("code"), phoneLoginController.RequestCode)
+		authRoutes.POST("/login/phone/otp/verify", rateLimit("login"), phoneLoginController.VerifyCode)
+		authRoutes.POST("/login/mfa", rateLimit("login"), mfaController.LoginWithMFA)
+		authRoutes.POST("/login/oidc/callback", rateLimit("login"), oidcController.Callback)
+		authRoutes.POST("/login/oidc/:provider", rateLimit("login"), oidcController.BeginLogin)
+		authRoutes.POST("/webauthn/login/begin", rateLimit("login"), webAuthnController.BeginLogin)
+		authRoutes.POST("/webauthn/login/finish", rateLimit("login"), webAuthnController.FinishLogin)
+		authRoutes.POST("/register/email", rateLimit("register"), authController.RegisterWithEmail)
+		authRoutes.POST("/register/phone", rateLimit("register"), authController.RegisterWithPhone)
+		authRoutes.POST("/token/refresh", rateLimit("refresh"), tokenController.Refresh)
+		authRoutes.POST("/verify/email", verificationController.VerifyEmail)
+		authRoutes.POST("/password/forgot", rateLimit("code"), passwordController.ForgotPassword)
+		authRoutes.POST("/password/reset", rateLimit("login"), passwordController.ResetPassword)
+	}
+
+	protectedRoutes := router.Group("/v1", middleware.Authenticate(tokenService, apiKeyService))
+	{
+		protectedRoutes.GET("/sessions", middleware.RequireScope(services.ScopeSessionsRead), sessionController.List)
+		protectedRoutes.GET("/user", middleware.RequireScope(services.ScopeProfileRead), userController.Profile)
+	}
+
+	// Routes that manage the account's credentials or sessions; API keys and
+	// admins impersonating the user may not use them.
+	userLoginRoutes := protectedRoutes.Group("", middleware.RequireUserLogin(), middleware.ForbidImpersonation())
+	{
+		userLoginRoutes.POST("/logout", tokenController.Logout)
+		userLoginRoutes.DELETE("/sessions/:id", sessionController.Revoke)
+		userLoginRoutes.POST("/sessions/revoke-others", sessionController.RevokeOthers)
+		userLoginRoutes.POST("/verify/email/resend", rateLimit("code"), verificationController.ResendEmailVerification)
+		userLoginRoutes.POST("/verify/phone/request", rateLimit("code"), verificationController.RequestPhoneVerification)
+		userLoginRoutes.POST("/verify/phone/confirm", verificationController.ConfirmPhoneVerification)
+		userLoginRoutes.PUT("/user/password", passwordController.ChangePassword)
+	}
+
+	// Routes that unverified accounts may not use when REQUIRE_EMAIL_VERIFICATION is on.
+	verifiedRoutes := userLoginRoutes.Group("")
+	if cfg.RequireEmailVerification {
+		verifiedRoutes.Use(middleware.RequireVerifiedEmail())
+	}
+	{
+		verifiedRoutes.POST("/user/mfa/totp", mfaController.EnrollTOTP)
+		verifiedRoutes.POST("/user/mfa/totp/confirm", mfaController.ConfirmTOTP)
+		verifiedRoutes.DELETE("/user/mfa/totp", mfaController.DisableTOTP)
+		verifiedRoutes.POST("/webauthn/register/begin", webAuthnController.BeginRegistration)
+		verifiedRoutes.POST("/webauthn/register/finish", webAuthnController.FinishRegistration)
+		verifiedRoutes.GET("/user/api-keys", apiKeyController.List)
+		verifiedRoutes.POST("/user/api-keys", apiKeyController.Create)
+		verifiedRoutes.DELETE("/user/api-keys/:id", apiKeyController.Revoke)
+	}
+
+	adminRoutes := router.Group("/v1/admin", middleware.Authenticate(tokenService, apiKeyService), middleware.RequireUserLogin())
+	manageRoles := middleware.RequirePermission(roleService, services.PermissionRolesManage)
+	{
+		adminRoutes.GET("/users/:id/roles", manageRoles, roleController.List)
+		adminRoutes.POST("/users/:id/roles", manageRoles, roleController.Grant)
+		adminRoutes.DELETE("/users/:id/roles/:role", manageRoles, roleController.Revoke)
+		adminRoutes.POST("/impersonate/:userId", middleware.RequirePermission(roleService, services.PermissionImpersonate), impersonationController.Start)
+		// Called with the impersonation token, which carries the user's roles.
+		adminRoutes.DELETE("/impersonate", impersonationController.Stop)
+	}
+
+	internalRoutes := router.Group("/v1/internal", middleware.RequireServiceCredential(cfg.ServiceClients))
+	{
+		internalRoutes.POST("/introspect", tokenController.Introspect)
+		internalRoutes.POST("/users/:id/unlock", adminController.UnlockUser)
 	}
 
 	port := os.Getenv("PORT")
@@ -42,3 +216,19 @@ func main() {
 
 	defer db.CloseDB()
 }
+
+// parseRateLimits reads the configured limits per route group and returns
+// the longest period among them.
+func parseRateLimits(specs map[string]string) (map[string]ratelimit.Limit, time.Duration) {
+	limits := make(map[string]ratelimit.Limit, len(specs))
+	var longest time.Duration
+	for route, spec := range specs {
+		limit, err := ratelimit.ParseLimit(spec)
+		if err != nil {
+			log.Fatalf("Invalid rate limit for %s: %v", route, err)
+		}
+		limits[route] = limit
+		longest = max(longest, limit.Period)
+	}
+	return limits, longest
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"errors"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authUserKey   = "authUser"
+	authClaimsKey = "authClaims"
+	authAPIKeyKey = "authAPIKey"
+)
+
+// Authenticate validates the bearer token in the Authorization header and
+// stores the user it belongs to in the context. Machine clients may send
+// "ApiKey <key>" instead, which resolves to the user owning the key; such
+// requests have no claims and are limited by RequireScope and
+// RequireUserLogin. A nil apiKeyService accepts bearer tokens only.
+// Requests without a valid credential are aborted with 401. Requests made
+// with an impersonation token are logged with the admin acting.
+func Authenticate(tokenService services.TokenService, apiKeyService services.APIKeyService) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scheme, credential, ok := authorization(ctx.GetHeader("Authorization"))
+		switch {
+		case ok && strings.EqualFold(scheme, "Bearer"):
+			user, claims, err := tokenService.VerifyAccessToken(credential)
+			if err != nil {
+				abortAuthError(ctx, err)
+				return
+			}
+			ctx.Set(authUserKey, user)
+			ctx.Set(authClaimsKey, claims)
+			if claims.Actor != nil {
+				log.Printf("Impersonated request by user %s as user %s: %s %s", claims.Actor.Subject, claims.Subject, ctx.Request.Method, ctx.Request.URL.Path)
+			}
+		case ok && strings.EqualFold(scheme, "ApiKey") && apiKeyService != nil:
+			user, key, err := apiKeyService.Authenticate(credential)
+			if err != nil {
+				abortAuthError(ctx, err)
+				return
+			}
+			ctx.Set(authUserKey, user)
+			ctx.Set(authAPIKeyKey, key)
+		default:
+			abortUnauthorized(ctx, "missing or malformed authorization header")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireScope admits API keys only if they were granted the scope. Logged
+// in users have every scope. It must run after Authenticate.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if key, ok := CurrentAPIKey(ctx); ok && !services.APIKeyHasScope(key, scope) {
+			utils.RespondError(ctx, http.StatusForbidden, services.ErrInsufficientScope.Error())
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequireUserLogin rejects API keys, for routes that manage the account's
+// credentials or sessions: a leaked key must not be enough to take the
+// account over. It must run after Authenticate.
+func RequireUserLogin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, ok := CurrentClaims(ctx); !ok {
+			utils.RespondError(ctx, http.StatusForbidden, "this endpoint cannot be used with an api key")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RequirePermission admits requests whose user holds a role granting every
+// given permission. Access tokens are checked against the roles they carry;
+// API keys carry none, so the roles are looked up. It must run after
+// Authenticate.
+func RequirePermission(roleService services.RoleService, permissions ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var roles []string
+		if claims, ok := CurrentClaims(ctx); ok {
+			roles = claims.Roles
+		} else {
+			user, _ := CurrentUser(ctx)
+			var err error
+			if roles, err = roleService.UserRoles(user.ID); err != nil {
+				utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+				ctx.Abort()
+				return
+			}
+		}
+
+		for _, permission := range permissions {
+			if !roleService.HasPermission(roles, permission) {
+				utils.RespondError(ctx, http.StatusForbidden, services.ErrPermissionDenied.Error())
+				ctx.Abort()
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// ForbidImpersonation rejects impersonation tokens, for sensitive actions
+// such as changing the password or payout bank details that only the user
+// may take. It must run after Authenticate.
+func ForbidImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if claims, ok := CurrentClaims(ctx); ok && claims.Actor != nil {
+			utils.RespondError(ctx, http.StatusForbidden, services.ErrImpersonating.Error())
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// CurrentUser returns the user stored by Authenticate.
+func CurrentUser(ctx *gin.Context) (*models.User, bool) {
+	value, exists := ctx.Get(authUserKey)
+	if !exists {
+		return nil, false
+	}
+	user, ok := value.(*models.User)
+	return user, ok
+}
+
+// CurrentClaims returns the claims of the access token used for the request.
+func CurrentClaims(ctx *gin.Context) (*utils.Claims, bool) {
+	value, exists := ctx.Get(authClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*utils.Claims)
+	return claims, ok
+}
+
+// CurrentAPIKey returns the API key the request was authenticated with, if
+// any.
+func CurrentAPIKey(ctx *gin.Context) (*models.APIKey, bool) {
+	value, exists := ctx.Get(authAPIKeyKey)
+	if !exists {
+		return nil, false
+	}
+	key, ok := value.(*models.APIKey)
+	return key, ok
+}
+
+func authorization(header string) (string, string, bool) {
+	scheme, credential, found := strings.Cut(header, " ")
+	credential = strings.TrimSpace(credential)
+	return scheme, credential, found && credential != ""
+}
+
+func abortAuthError(ctx *gin.Context, err error) {
+	if errors.Is(err, utils.ErrInternal) {
+		utils.RespondError(ctx, http.StatusInternalServerError, utils.ErrInternal.Error())
+		ctx.Abort()
+		return
+	}
+	abortUnauthorized(ctx, err.Error())
+}
+
+func abortUnauthorized(ctx *gin.Context, message string) {
+	utils.RespondError(ctx, http.StatusUnauthorized, message)
+	ctx.Abort()
+}
@@ -0,0 +1,247 @@
+package middleware_test
+
+import (
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+
+	router := utils.SetupRouter()
+	router.GET("/v1/user", middleware.Authenticate(mockTokenService, nil), func(ctx *gin.Context) {
+		user, ok := middleware.CurrentUser(ctx)
+		if !ok {
+			ctx.Status(http.StatusTeapot)
+			return
+		}
+		claims, ok := middleware.CurrentClaims(ctx)
+		if !ok {
+			ctx.Status(http.StatusTeapot)
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"id": user.ID, "jti": claims.ID})
+	})
+
+	doRequest := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/user", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Valid Token", func(t *testing.T) {
+		claims := &utils.Claims{LoginMethod: "email"}
+		claims.ID = "jti123"
+		claims.Subject = "1"
+		mockTokenService.On("VerifyAccessToken", "valid").
+			Return(&models.User{ID: 1, Email: utils.NewNullableString("name@name.com")}, claims, nil)
+
+		resp := doRequest("Bearer valid")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"id":1, "jti":"jti123"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Missing Header", func(t *testing.T) {
+		resp := doRequest("")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"missing or malformed authorization header"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Wrong Scheme", func(t *testing.T) {
+		resp := doRequest("Basic dXNlcjpwYXNz")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+
+	t.Run("401 Unauthorized - Invalid Token", func(t *testing.T) {
+		mockTokenService.On("VerifyAccessToken", "expired").
+			Return(nil, nil, utils.ErrInvalidToken)
+
+		resp := doRequest("Bearer expired")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired token"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - Revoked Token", func(t *testing.T) {
+		mockTokenService.On("VerifyAccessToken", "revoked").
+			Return(nil, nil, services.ErrTokenRevoked)
+
+		resp := doRequest("Bearer revoked")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"token has been revoked"}`, resp.Body.String())
+	})
+
+	t.Run("500 Internal Server Error", func(t *testing.T) {
+		mockTokenService.On("VerifyAccessToken", "broken").
+			Return(nil, nil, utils.ErrInternal)
+
+		resp := doRequest("Bearer broken")
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	})
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockAPIKeyService := new(services.APIKeyServiceMock)
+
+	router := utils.SetupRouter()
+	protected := router.Group("/v1", middleware.Authenticate(mockTokenService, mockAPIKeyService))
+	protected.GET("/user", middleware.RequireScope(services.ScopeProfileRead), func(ctx *gin.Context) {
+		user, _ := middleware.CurrentUser(ctx)
+		_, hasClaims := middleware.CurrentClaims(ctx)
+		key, _ := middleware.CurrentAPIKey(ctx)
+		ctx.JSON(http.StatusOK, gin.H{"id": user.ID, "claims": hasClaims, "key": key.ID})
+	})
+	protected.GET("/sessions", middleware.RequireScope(services.ScopeSessionsRead), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	protected.PUT("/user/password", middleware.RequireUserLogin(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	doRequest := func(method, path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", authorization)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	user := &models.User{ID: 1}
+	key := &models.APIKey{ID: 5, UserID: 1, Scopes: services.ScopeProfileRead}
+	mockAPIKeyService.On("Authenticate", "tlk_valid").Return(user, key, nil)
+
+	t.Run("200 OK - Resolves To The Key Owner", func(t *testing.T) {
+		resp := doRequest(http.MethodGet, "/v1/user", "ApiKey tlk_valid")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"id":1, "claims":false, "key":5}`, resp.Body.String())
+	})
+
+	t.Run("403 Forbidden - Scope Not Granted", func(t *testing.T) {
+		resp := doRequest(http.MethodGet, "/v1/sessions", "ApiKey tlk_valid")
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"api key does not grant this scope"}`, resp.Body.String())
+	})
+
+	t.Run("403 Forbidden - Credential Management", func(t *testing.T) {
+		resp := doRequest(http.MethodPut, "/v1/user/password", "ApiKey tlk_valid")
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+	})
+
+	t.Run("200 OK - Access Tokens Have Every Scope", func(t *testing.T) {
+		mockTokenService.On("VerifyAccessToken", "valid").Return(user, &utils.Claims{}, nil)
+
+		assert.Equal(t, http.StatusOK, doRequest(http.MethodGet, "/v1/sessions", "Bearer valid").Code)
+		assert.Equal(t, http.StatusOK, doRequest(http.MethodPut, "/v1/user/password", "Bearer valid").Code)
+	})
+
+	t.Run("401 Unauthorized - Invalid Key", func(t *testing.T) {
+		mockAPIKeyService.On("Authenticate", "tlk_revoked").Return(nil, nil, services.ErrInvalidAPIKey)
+
+		resp := doRequest(http.MethodGet, "/v1/user", "ApiKey tlk_revoked")
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+		assert.JSONEq(t, `{"error":"invalid or expired api key"}`, resp.Body.String())
+	})
+
+	t.Run("401 Unauthorized - API Keys Not Accepted", func(t *testing.T) {
+		router := utils.SetupRouter()
+		router.GET("/v1/user", middleware.Authenticate(mockTokenService, nil), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/v1/user", nil)
+		req.Header.Set("Authorization", "ApiKey tlk_valid")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	})
+}
+
+func TestRequirePermission(t *testing.T) {
+	mockTokenService := new(services.TokenServiceMock)
+	mockAPIKeyService := new(services.APIKeyServiceMock)
+	mockRoleRepo := new(repositories.RoleRepositoryMock)
+	mockRoleRepo.On("ListRolePermissions").Return(map[string][]string{
+		services.RoleBuyer:  {services.PermissionOrdersCreate},
+		services.RoleSeller: {services.PermissionProductsManage},
+		services.RoleAdmin:  {services.PermissionRolesManage, services.PermissionUsersRead},
+	}, nil)
+	roleService, err := services.NewRoleService(mockRoleRepo, nil, nil, config.Config{DefaultRoles: []string{services.RoleBuyer}})
+	require.NoError(t, err)
+
+	router := utils.SetupRouter()
+	protected := router.Group("/v1", middleware.Authenticate(mockTokenService, mockAPIKeyService))
+	protected.GET("/products", middleware.RequirePermission(roleService, services.PermissionProductsManage), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+	protected.GET("/admin/users", middleware.RequirePermission(roleService, services.PermissionUsersRead, services.PermissionRolesManage), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	doRequest := func(path, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", authorization)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	user := &models.User{ID: 1}
+	mockTokenService.On("VerifyAccessToken", "buyer").Return(user, &utils.Claims{Roles: []string{services.RoleBuyer}}, nil)
+	mockTokenService.On("VerifyAccessToken", "seller").Return(user, &utils.Claims{Roles: []string{services.RoleBuyer, services.RoleSeller}}, nil)
+	mockTokenService.On("VerifyAccessToken", "admin").Return(user, &utils.Claims{Roles: []string{services.RoleAdmin}}, nil)
+
+	t.Run("200 OK - Role Grants Permission", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, doRequest("/v1/products", "Bearer seller").Code)
+		assert.Equal(t, http.StatusOK, doRequest("/v1/admin/users", "Bearer admin").Code)
+	})
+
+	t.Run("403 Forbidden - No Role Grants Permission", func(t *testing.T) {
+		resp := doRequest("/v1/products", "Bearer buyer")
+
+		assert.Equal(t, http.StatusForbidden, resp.Code)
+		assert.JSONEq(t, `{"error":"you do not have permission to do this"}`, resp.Body.String())
+	})
+
+	t.Run("403 Forbidden - Every Permission Is Required", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, doRequest("/v1/admin/users", "Bearer seller").Code)
+	})
+
+	t.Run("200 OK - API Key Uses The Owner's Stored Roles", func(t *testing.T) {
+		mockAPIKeyService.On("Authenticate", "tlk_valid").Return(&models.User{ID: 2}, &models.APIKey{ID: 5, UserID: 2}, nil)
+		mockRoleRepo.On("ListUserRoles", 2).Return([]string{services.RoleSeller}, nil)
+
+		assert.Equal(t, http.StatusOK, doRequest("/v1/products", "ApiKey tlk_valid").Code)
+		assert.Equal(t, http.StatusForbidden, doRequest("/v1/admin/users", "ApiKey tlk_valid").Code)
+	})
+
+	t.Run("Unknown Default Role", func(t *testing.T) {
+		_, err := services.NewRoleService(mockRoleRepo, nil, nil, config.Config{DefaultRoles: []string{"guest"}})
+
+		assert.ErrorIs(t, err, services.ErrUnknownRole)
+	})
+}
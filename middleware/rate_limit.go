@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-tutuplapak-user/ratelimit"
+	"go-tutuplapak-user/utils"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// identifierFields are the request body fields that name the account an
+// auth request is about.
+var identifierFields = []string{"identifier", "email", "phone"}
+
+// maxRateLimitedBodyBytes bounds the body read for the identifier, which
+// happens before any handler has validated the request.
+const maxRateLimitedBodyBytes = 64 << 10
+
+// RateLimit throttles a group of routes with one bucket per client IP and one
+// per submitted email or phone, so neither a single client nor a distributed
+// attack on one account gets unlimited attempts. Every response carries the
+// RateLimit-* headers of the tighter bucket. A zero Limit disables that
+// dimension, and limiter failures let the request through.
+func RateLimit(limiter ratelimit.Limiter, route string, byIP, byIdentifier ratelimit.Limit) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var results []ratelimit.Result
+
+		if byIP.Burst > 0 {
+			result, ok := allow(limiter, route+":ip:"+ctx.ClientIP(), byIP)
+			if ok {
+				results = append(results, result)
+			}
+		}
+
+		identifier, err := requestIdentifier(ctx)
+		if err != nil {
+			utils.RespondError(ctx, http.StatusRequestEntityTooLarge, "request body is too large")
+			ctx.Abort()
+			return
+		}
+
+		if identifier != "" && byIdentifier.Burst > 0 {
+			if len(results) == 0 || results[0].Allowed {
+				result, ok := allow(limiter, route+":id:"+identifier, byIdentifier)
+				if ok {
+					results = append(results, result)
+				}
+			}
+		}
+
+		if len(results) == 0 {
+			ctx.Next()
+			return
+		}
+
+		result := tightest(results)
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			utils.RespondError(ctx, http.StatusTooManyRequests, "too many requests, try again later")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func allow(limiter ratelimit.Limiter, key string, limit ratelimit.Limit) (ratelimit.Result, bool) {
+	result, err := limiter.Allow(key, limit)
+	if err != nil {
+		log.Printf("Rate limiter failed for %s: %v", key, err)
+		return ratelimit.Result{}, false
+	}
+	return result, true
+}
+
+// tightest picks a denied result if there is one, otherwise the one with the
+// fewest requests left.
+func tightest(results []ratelimit.Result) ratelimit.Result {
+	picked := results[0]
+	for _, result := range results[1:] {
+		if !result.Allowed || (picked.Allowed && result.Remaining < picked.Remaining) {
+			picked = result
+		}
+	}
+	return picked
+}
+
+// requestIdentifier reads the email or phone from a JSON body and puts the
+// body back for the handler. It fails only for bodies over
+// maxRateLimitedBodyBytes.
+func requestIdentifier(ctx *gin.Context) (string, error) {
+	if ctx.Request.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxRateLimitedBodyBytes))
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return "", err
+		}
+		return "", nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", nil
+	}
+
+	for _, name := range identifierFields {
+		if value, ok := fields[name].(string); ok && strings.TrimSpace(value) != "" {
+			return normalizeIdentifier(value), nil
+		}
+	}
+	return "", nil
+}
+
+// normalizeIdentifier maps every spelling of an identifier that reaches the
+// same account to one bucket, e.g. phone numbers with or without separators.
+func normalizeIdentifier(value string) string {
+	if _, normalized, ok := utils.ClassifyIdentifier(value); ok {
+		return strings.ToLower(normalized)
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,127 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-tutuplapak-user/middleware"
+	"go-tutuplapak-user/ratelimit"
+	"go-tutuplapak-user/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	byIP := ratelimit.Limit{Burst: 3, Period: time.Minute}
+	byIdentifier := ratelimit.Limit{Burst: 2, Period: time.Minute}
+
+	router := utils.SetupRouter()
+	router.SetTrustedProxies(nil)
+	router.POST("/v1/login/email", middleware.RateLimit(limiter, "login", byIP, byIdentifier), func(ctx *gin.Context) {
+		var req struct {
+			Email string `json:"email"`
+		}
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.Status(http.StatusBadRequest)
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"email": req.Email})
+	})
+
+	doRequest := func(ip, email string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": "asdfasdf"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":12345"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("200 OK - Body Still Readable And Headers Set", func(t *testing.T) {
+		resp := doRequest("10.0.0.1", "name@name.com")
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.JSONEq(t, `{"email":"name@name.com"}`, resp.Body.String())
+		assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+		assert.NotEmpty(t, resp.Header().Get("RateLimit-Reset"))
+	})
+
+	t.Run("429 Too Many Requests - Identifier Limit Across IPs", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, doRequest("10.0.0.2", "NAME@name.com").Code)
+
+		resp := doRequest("10.0.0.3", "name@name.com")
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"error":"too many requests, try again later"}`, resp.Body.String())
+	})
+
+	t.Run("429 Too Many Requests - IP Limit Across Identifiers", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, doRequest("10.0.0.4", "a@name.com").Code)
+		assert.Equal(t, http.StatusOK, doRequest("10.0.0.4", "b@name.com").Code)
+		assert.Equal(t, http.StatusOK, doRequest("10.0.0.4", "c@name.com").Code)
+
+		resp := doRequest("10.0.0.4", "d@name.com")
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+		assert.Equal(t, "3", resp.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "20", resp.Header().Get("Retry-After"))
+	})
+
+	t.Run("429 Too Many Requests - Forwarded For Is Ignored", func(t *testing.T) {
+		forwardedRequest := func(forwardedFor string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]string{"email": forwardedFor + "@name.com"})
+			req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			req.RemoteAddr = "10.0.0.5:12345"
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			return resp
+		}
+
+		for _, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+			assert.Equal(t, http.StatusOK, forwardedRequest(ip).Code)
+		}
+
+		assert.Equal(t, http.StatusTooManyRequests, forwardedRequest("4.4.4.4").Code)
+	})
+
+	t.Run("429 Too Many Requests - Phone Separators Share A Bucket", func(t *testing.T) {
+		phoneRequest := func(ip, phone string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]string{"phone": phone, "password": "asdfasdf"})
+			req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.RemoteAddr = ip + ":12345"
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+			return resp
+		}
+
+		assert.Equal(t, http.StatusOK, phoneRequest("10.0.0.7", "+628123456789").Code)
+		assert.Equal(t, http.StatusOK, phoneRequest("10.0.0.8", "+62 812 345 6789").Code)
+
+		resp := phoneRequest("10.0.0.9", "+62-812-345-6789")
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	})
+
+	t.Run("413 Request Entity Too Large - Oversized Body", func(t *testing.T) {
+		body := append([]byte(`{"email":"`), bytes.Repeat([]byte("a"), 1<<20)...)
+		req := httptest.NewRequest(http.MethodPost, "/v1/login/email", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.6:12345"
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	})
+}
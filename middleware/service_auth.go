@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const serviceClientKey = "serviceClient"
+
+// RequireServiceCredential admits only internal services presenting one of
+// the configured client ID/secret pairs through HTTP Basic authentication.
+func RequireServiceCredential(clients map[string]string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		clientID, secret, ok := ctx.Request.BasicAuth()
+		expected, known := clients[clientID]
+		if !ok || !known || expected == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+			ctx.Header("WWW-Authenticate", `Basic realm="internal"`)
+			utils.RespondError(ctx, http.StatusUnauthorized, "invalid service credentials")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Set(serviceClientKey, clientID)
+		ctx.Next()
+	}
+}
+
+// CurrentServiceClient returns the client ID accepted by RequireServiceCredential.
+func CurrentServiceClient(ctx *gin.Context) (string, bool) {
+	clientID := ctx.GetString(serviceClientKey)
+	return clientID, clientID != ""
+}
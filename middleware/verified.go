@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"go-tutuplapak-user/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmail rejects users who registered with an email address
+// they have not confirmed yet. It must run after Authenticate.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := CurrentUser(ctx)
+		if !ok {
+			abortUnauthorized(ctx, "authentication required")
+			return
+		}
+
+		if user.Email.Valid && !user.EmailVerifiedAt.Valid {
+			utils.RespondError(ctx, http.StatusForbidden, "email address is not verified")
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
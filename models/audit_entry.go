@@ -0,0 +1,16 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type AuditEntry struct {
+	ID           int           `json:"id"`
+	ActorID      sql.NullInt64 `json:"actor_id"`
+	Action       string        `json:"action"`
+	TargetUserID sql.NullInt64 `json:"target_user_id"`
+	Details      string        `json:"details"`
+	IPAddress    string        `json:"ip_address"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
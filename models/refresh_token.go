@@ -0,0 +1,19 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type RefreshToken struct {
+	ID          int           `json:"id"`
+	UserID      int           `json:"user_id"`
+	SessionID   sql.NullInt64 `json:"session_id"`
+	FamilyID    string        `json:"family_id"`
+	LoginMethod string        `json:"login_method"`
+	TokenHash   string        `json:"-"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	RotatedAt   sql.NullTime  `json:"rotated_at"`
+	RevokedAt   sql.NullTime  `json:"revoked_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
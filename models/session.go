@@ -0,0 +1,17 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Session struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"user_id"`
+	DeviceName string       `json:"device_name"`
+	UserAgent  string       `json:"user_agent"`
+	IPAddress  string       `json:"ip_address"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastSeenAt time.Time    `json:"last_seen_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+}
@@ -13,6 +13,14 @@ type User struct {
 	BankAccountName   string         `json:"bank_account_name"`
 	BankAccountHolder string         `json:"bank_account_holder"`
 	BankAccountNumber string         `json:"bank_account_number"`
+	EmailVerifiedAt   sql.NullTime   `json:"email_verified_at"`
+	PhoneVerifiedAt   sql.NullTime   `json:"phone_verified_at"`
+	PasswordChangedAt sql.NullTime   `json:"password_changed_at"`
+	FailedLogins      int            `json:"failed_login_attempts"`
+	LockedUntil       sql.NullTime   `json:"locked_until"`
+	TOTPSecret        sql.NullString `json:"-"`
+	TOTPEnabledAt     sql.NullTime   `json:"totp_enabled_at"`
+	TOTPLastStep      int64          `json:"-"`
 	CreatedAt         string         `json:"created_at"`
 	UpdatedAt         string         `json:"updated_at"`
 }
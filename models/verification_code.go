@@ -0,0 +1,18 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type VerificationCode struct {
+	ID         int           `json:"id"`
+	UserID     sql.NullInt64 `json:"user_id"`
+	Purpose    string        `json:"purpose"`
+	Target     string        `json:"target"`
+	CodeHash   string        `json:"-"`
+	Attempts   int           `json:"attempts"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	ConsumedAt sql.NullTime  `json:"consumed_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
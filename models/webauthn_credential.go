@@ -0,0 +1,18 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+type WebAuthnCredential struct {
+	ID           int          `json:"id"`
+	UserID       int          `json:"user_id"`
+	CredentialID []byte       `json:"-"`
+	PublicKey    []byte       `json:"-"`
+	SignCount    uint32       `json:"sign_count"`
+	AAGUID       []byte       `json:"-"`
+	Transports   string       `json:"transports"`
+	CreatedAt    time.Time    `json:"created_at"`
+	LastUsedAt   sql.NullTime `json:"last_used_at"`
+}
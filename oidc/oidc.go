@@ -0,0 +1,320 @@
+// Package oidc signs users in through external OpenID Connect providers
+// using the authorization code flow with PKCE. Only what the service needs
+// is implemented: discovery, the token exchange and ID token validation
+// against the provider's published keys.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrExchangeFailed means the provider refused the authorization code.
+	ErrExchangeFailed = errors.New("oidc: code exchange failed")
+	// ErrInvalidIDToken means the ID token was not issued by the provider
+	// for this client and this login.
+	ErrInvalidIDToken = errors.New("oidc: invalid id token")
+)
+
+// Keys are fetched again for an unknown kid, but not more often than this,
+// so tokens with made up kids cannot hammer the provider.
+const jwksRefreshInterval = time.Minute
+
+// Provider is one configured identity provider.
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// IDToken holds the claims of a validated ID token that the service uses.
+type IDToken struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	Email           string `json:"email,omitempty"`
+	EmailVerified   bool   `json:"email_verified,omitempty"`
+	Name            string `json:"name,omitempty"`
+}
+
+// metadata is the part of the discovery document the client uses.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+type signingKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// Client talks to one provider. Its discovery document and keys are loaded
+// on first use and cached.
+type Client struct {
+	provider   Provider
+	httpClient *http.Client
+
+	mu              sync.Mutex
+	metadata        *metadata
+	keys            map[string]signingKey
+	keysRefreshedAt time.Time
+}
+
+// NewClient returns a client for the provider. A nil httpClient uses one
+// with a ten second timeout.
+func NewClient(provider Provider, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{provider: provider, httpClient: httpClient}
+}
+
+// Issuer returns the issuer identifier the provider was configured with.
+func (c *Client) Issuer() string {
+	return c.provider.Issuer
+}
+
+// NewCodeVerifier returns a random PKCE code verifier.
+func NewCodeVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallengeS256 derives the PKCE code challenge sent with the
+// authorization request.
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL the browser is sent to.
+func (c *Client) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	meta, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: invalid authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.provider.ClientID)
+	query.Set("redirect_uri", c.provider.RedirectURL)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange redeems the authorization code and returns the raw ID token,
+// which must still be checked with VerifyIDToken.
+func (c *Client) Exchange(code, codeVerifier string) (string, error) {
+	meta, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.provider.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.provider.ClientID), url.QueryEscape(c.provider.ClientSecret))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("%w: status %d: %v", ErrExchangeFailed, resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: %s: %s", ErrExchangeFailed, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token in response", ErrExchangeFailed)
+	}
+	return token.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of
+// an ID token.
+func (c *Client) VerifyIDToken(raw, nonce string) (*IDToken, error) {
+	var claims idTokenClaims
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}))
+	if _, err := parser.ParseWithClaims(raw, &claims, c.keyfunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	switch {
+	case claims.Issuer != c.provider.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidIDToken, claims.Issuer)
+	case !claims.VerifyAudience(c.provider.ClientID, true):
+		return nil, fmt.Errorf("%w: not issued for this client", ErrInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != c.provider.ClientID:
+		return nil, fmt.Errorf("%w: authorized party %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	return &IDToken{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+func (c *Client) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, err := c.signingKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// signingKey finds a key by kid, fetching the key set again when the kid is
+// new, as providers publish the next key shortly before rotating to it.
+func (c *Client) signingKey(kid string) (signingKey, error) {
+	meta, err := c.discover()
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	if c.keys != nil {
+		if time.Since(c.keysRefreshedAt) < jwksRefreshInterval {
+			return signingKey{}, fmt.Errorf("unknown key id %q", kid)
+		}
+		c.keysRefreshedAt = time.Now()
+	}
+
+	var jwks utils.JWKS
+	if err := c.getJSON(meta.JWKSURI, &jwks); err != nil {
+		return signingKey{}, err
+	}
+
+	keys := make(map[string]signingKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		method, key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		if jwk.Algorithm != "" && jwk.Algorithm != method.Alg() {
+			continue
+		}
+		keys[jwk.KeyID] = signingKey{method: method, key: key}
+	}
+	c.keys = keys
+
+	key, ok := c.keys[kid]
+	if !ok {
+		return signingKey{}, fmt.Errorf("unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+func (c *Client) discover() (*metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.metadata != nil {
+		return c.metadata, nil
+	}
+
+	var meta metadata
+	if err := c.getJSON(strings.TrimSuffix(c.provider.Issuer, "/")+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, err
+	}
+	if meta.Issuer != c.provider.Issuer {
+		return nil, fmt.Errorf("oidc: discovery document is for issuer %q, expected %q", meta.Issuer, c.provider.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document is missing endpoints")
+	}
+
+	c.metadata = &meta
+	return c.metadata, nil
+}
+
+func (c *Client) getJSON(target string, out interface{}) error {
+	resp, err := c.httpClient.Get(target)
+	if err != nil {
+		return fmt.Errorf("oidc: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s: status %d", target, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out); err != nil {
+		return fmt.Errorf("oidc: GET %s: %w", target, err)
+	}
+	return nil
+}
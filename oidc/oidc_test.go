@@ -0,0 +1,190 @@
+package oidc_test
+
+import (
+	"go-tutuplapak-user/oidc"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8080/login/oidc/callback"
+
+func startProvider(t *testing.T) (*oidc.FakeProvider, *oidc.Client) {
+	t.Helper()
+	provider, err := oidc.NewFakeProvider("client123", "secret123")
+	require.NoError(t, err)
+	provider.User = oidc.FakeUser{Subject: "sub123", Email: "name@name.com", EmailVerified: true}
+
+	server := httptest.NewServer(provider)
+	t.Cleanup(server.Close)
+	provider.Issuer = server.URL
+
+	client := oidc.NewClient(oidc.Provider{
+		Issuer:       server.URL,
+		ClientID:     "client123",
+		ClientSecret: "secret123",
+		RedirectURL:  redirectURL,
+	}, nil)
+	return provider, client
+}
+
+// authorize follows the authorization URL the way a browser would and
+// returns the code and state from the redirect back.
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := browser.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestClient(t *testing.T) {
+	provider, client := startProvider(t)
+
+	login := func(t *testing.T, verifier, nonce string) (string, error) {
+		t.Helper()
+		authURL, err := client.AuthCodeURL("state123", nonce, oidc.CodeChallengeS256(verifier))
+		require.NoError(t, err)
+		code, state := authorize(t, authURL)
+		assert.Equal(t, "state123", state)
+		return client.Exchange(code, verifier)
+	}
+
+	t.Run("Authorization Code With PKCE", func(t *testing.T) {
+		verifier, err := oidc.NewCodeVerifier()
+		require.NoError(t, err)
+
+		raw, err := login(t, verifier, "nonce123")
+		require.NoError(t, err)
+		token, err := client.VerifyIDToken(raw, "nonce123")
+
+		require.NoError(t, err)
+		assert.Equal(t, &oidc.IDToken{Subject: "sub123", Email: "name@name.com", EmailVerified: true}, token)
+	})
+
+	t.Run("Wrong Code Verifier", func(t *testing.T) {
+		authURL, err := client.AuthCodeURL("state123", "nonce123", oidc.CodeChallengeS256("verifier123"))
+		require.NoError(t, err)
+		code, _ := authorize(t, authURL)
+
+		_, err = client.Exchange(code, "another verifier")
+
+		assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+		assert.ErrorContains(t, err, "code_verifier mismatch")
+	})
+
+	t.Run("Code Is Single Use", func(t *testing.T) {
+		authURL, err := client.AuthCodeURL("state123", "nonce123", oidc.CodeChallengeS256("verifier123"))
+		require.NoError(t, err)
+		code, _ := authorize(t, authURL)
+		_, err = client.Exchange(code, "verifier123")
+		require.NoError(t, err)
+
+		_, err = client.Exchange(code, "verifier123")
+
+		assert.ErrorIs(t, err, oidc.ErrExchangeFailed)
+	})
+
+	t.Run("Nonce Of Another Login", func(t *testing.T) {
+		raw, err := login(t, "verifier123", "nonce123")
+		require.NoError(t, err)
+
+		_, err = client.VerifyIDToken(raw, "nonce456")
+
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		assert.ErrorContains(t, err, "nonce mismatch")
+	})
+
+	t.Run("Issued For Another Client", func(t *testing.T) {
+		raw, err := provider.IDToken(jwt.MapClaims{
+			"iss": provider.Issuer, "aud": "client456", "sub": "sub123", "nonce": "nonce123",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err)
+
+		_, err = client.VerifyIDToken(raw, "nonce123")
+
+		assert.ErrorContains(t, err, "not issued for this client")
+	})
+
+	t.Run("Issued By Another Issuer", func(t *testing.T) {
+		raw, err := provider.IDToken(jwt.MapClaims{
+			"iss": "https://evil.example", "aud": "client123", "sub": "sub123", "nonce": "nonce123",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err)
+
+		_, err = client.VerifyIDToken(raw, "nonce123")
+
+		assert.ErrorContains(t, err, `issuer "https://evil.example"`)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		raw, err := provider.IDToken(jwt.MapClaims{
+			"iss": provider.Issuer, "aud": "client123", "sub": "sub123", "nonce": "nonce123",
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		})
+		require.NoError(t, err)
+
+		_, err = client.VerifyIDToken(raw, "nonce123")
+
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		assert.ErrorContains(t, err, "expired")
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		raw, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+			"iss": provider.Issuer, "aud": "client123", "sub": "sub123", "nonce": "nonce123",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = client.VerifyIDToken(raw, "nonce123")
+
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	})
+}
+
+func TestKeyRotation(t *testing.T) {
+	provider, client := startProvider(t)
+
+	verify := func() error {
+		raw, err := provider.IDToken(jwt.MapClaims{
+			"iss": provider.Issuer, "aud": "client123", "sub": "sub123", "nonce": "nonce123",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		require.NoError(t, err)
+		_, err = client.VerifyIDToken(raw, "nonce123")
+		return err
+	}
+
+	require.NoError(t, verify())
+
+	t.Run("New Key Is Fetched", func(t *testing.T) {
+		require.NoError(t, provider.RotateKey())
+
+		assert.NoError(t, verify())
+	})
+
+	t.Run("Refetched At Most Once A Minute", func(t *testing.T) {
+		require.NoError(t, provider.RotateKey())
+
+		err := verify()
+
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		assert.ErrorContains(t, err, "unknown key id")
+	})
+}
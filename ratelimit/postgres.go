@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// PostgresLimiter stores buckets in the rate_limit_buckets table so every
+// instance draws from the same budget. Each call locks the row it updates.
+type PostgresLimiter struct {
+	db *sql.DB
+}
+
+func NewPostgresLimiter(db *sql.DB) *PostgresLimiter {
+	return &PostgresLimiter{db: db}
+}
+
+func (l *PostgresLimiter) Allow(key string, limit Limit) (Result, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return Result{}, fmt.Errorf("error starting rate limit transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	insert := "INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING"
+	if _, err := tx.Exec(insert, key, float64(limit.Burst), now); err != nil {
+		return Result{}, fmt.Errorf("error creating rate limit bucket: %w", err)
+	}
+
+	var (
+		tokens    float64
+		updatedAt time.Time
+	)
+	query := "SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE"
+	if err := tx.QueryRow(query, key).Scan(&tokens, &updatedAt); err != nil {
+		return Result{}, fmt.Errorf("error loading rate limit bucket: %w", err)
+	}
+
+	tokens, result := take(tokens, updatedAt, now, limit)
+
+	update := "UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE key = $1"
+	if _, err := tx.Exec(update, key, tokens, now); err != nil {
+		return Result{}, fmt.Errorf("error updating rate limit bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("error committing rate limit bucket: %w", err)
+	}
+	return result, nil
+}
+
+// Start deletes buckets untouched for longer than maxPeriod every interval.
+// Such buckets are full again, so dropping them changes nothing.
+func (l *PostgresLimiter) Start(interval, maxPeriod time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			query := "DELETE FROM rate_limit_buckets WHERE updated_at < $1"
+			if _, err := l.db.Exec(query, time.Now().UTC().Add(-maxPeriod)); err != nil {
+				log.Printf("Failed to prune rate limit buckets: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit describes a token bucket holding up to Burst requests that refills
+// completely over Period.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Result is the outcome of one Allow call. RetryAfter is only set when the
+// request was denied; Reset is how long until the bucket is full again.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter takes one request from the bucket stored under key.
+type Limiter interface {
+	Allow(key string, limit Limit) (Result, error)
+}
+
+// New builds the limiter selected by RATE_LIMIT_DRIVER: "memory" for a single
+// instance, or "postgres" to share buckets between instances.
+func New(cfg config.Config, db *sql.DB) (Limiter, error) {
+	switch cfg.RateLimitDriver {
+	case "memory", "":
+		return NewMemoryLimiter(), nil
+	case "postgres":
+		return NewPostgresLimiter(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit driver %q", cfg.RateLimitDriver)
+	}
+}
+
+// ParseLimit reads limits written as "<burst>/<period>", e.g. "10/1m".
+func ParseLimit(value string) (Limit, error) {
+	burst, period, found := strings.Cut(value, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	n, err := strconv.Atoi(burst)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit burst %q", burst)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit period %q", period)
+	}
+
+	return Limit{Burst: n, Period: d}, nil
+}
+
+// take refills a bucket that last changed at updatedAt and removes one token
+// if there is one, returning the new token count and the result.
+func take(tokens float64, updatedAt, now time.Time, limit Limit) (float64, Result) {
+	rate := float64(limit.Burst) / limit.Period.Seconds()
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+elapsed*rate)
+	}
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((float64(limit.Burst) - tokens) / rate)
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
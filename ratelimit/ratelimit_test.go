@@ -0,0 +1,67 @@
+package ratelimit_test
+
+import (
+	"go-tutuplapak-user/ratelimit"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ratelimit.ParseLimit("10/1m")
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Burst: 10, Period: time.Minute}, limit)
+
+	for _, value := range []string{"", "10", "0/1m", "ten/1m", "10/soon", "10/-1m"} {
+		_, err := ratelimit.ParseLimit(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	limiter := ratelimit.NewMemoryLimiter()
+	limit := ratelimit.Limit{Burst: 2, Period: time.Hour}
+
+	t.Run("Allows The Burst Then Denies", func(t *testing.T) {
+		first, err := limiter.Allow("login:ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		assert.True(t, first.Allowed)
+		assert.Equal(t, 2, first.Limit)
+		assert.Equal(t, 1, first.Remaining)
+
+		second, err := limiter.Allow("login:ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		assert.True(t, second.Allowed)
+		assert.Equal(t, 0, second.Remaining)
+
+		third, err := limiter.Allow("login:ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		assert.False(t, third.Allowed)
+		assert.Equal(t, 0, third.Remaining)
+		// One token refills every half hour.
+		assert.InDelta(t, (30 * time.Minute).Seconds(), third.RetryAfter.Seconds(), 1)
+		assert.InDelta(t, time.Hour.Seconds(), third.Reset.Seconds(), 1)
+	})
+
+	t.Run("Keys Have Separate Buckets", func(t *testing.T) {
+		result, err := limiter.Allow("login:ip:10.0.0.2", limit)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	})
+
+	t.Run("Refills Over Time", func(t *testing.T) {
+		fast := ratelimit.Limit{Burst: 1, Period: 50 * time.Millisecond}
+
+		result, _ := limiter.Allow("code:id:name@name.com", fast)
+		assert.True(t, result.Allowed)
+		result, _ = limiter.Allow("code:id:name@name.com", fast)
+		assert.False(t, result.Allowed)
+
+		time.Sleep(60 * time.Millisecond)
+
+		result, _ = limiter.Allow("code:id:name@name.com", fast)
+		assert.True(t, result.Allowed)
+	})
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	FindByPrefix(prefix string) (*models.APIKey, error)
+	ListActiveByUser(userID int) ([]models.APIKey, error)
+	Touch(id int, lastUsedAt time.Time) error
+	Revoke(userID, id int) (bool, error)
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	query := `INSERT INTO api_keys (user_id, name, prefix, secret_hash, scopes, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	key.CreatedAt = time.Now().UTC()
+	return r.db.QueryRow(query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.SecretHash,
+		key.Scopes,
+		key.ExpiresAt,
+		key.CreatedAt,
+	).Scan(&key.ID)
+}
+
+func (r *apiKeyRepository) FindByPrefix(prefix string) (*models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+		FROM api_keys WHERE prefix = $1`
+
+	var key models.APIKey
+	err := scanAPIKey(r.db.QueryRow(query, prefix), &key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error querying api key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *apiKeyRepository) ListActiveByUser(userID int) ([]models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+		FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("error scanning api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) Touch(id int, lastUsedAt time.Time) error {
+	query := "UPDATE api_keys SET last_used_at = $2 WHERE id = $1"
+
+	_, err := r.db.Exec(query, id, lastUsedAt)
+	return err
+}
+
+// Revoke reports false when the user has no active key with this ID.
+func (r *apiKeyRepository) Revoke(userID, id int) (bool, error) {
+	query := "UPDATE api_keys SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL"
+
+	result, err := r.db.Exec(query, id, userID, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }, key *models.APIKey) error {
+	return row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.SecretHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		&key.RevokedAt,
+	)
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type APIKeyRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *APIKeyRepositoryMock) Create(key *models.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *APIKeyRepositoryMock) FindByPrefix(prefix string) (*models.APIKey, error) {
+	args := m.Called(prefix)
+	key, _ := args.Get(0).(*models.APIKey)
+	return key, args.Error(1)
+}
+
+func (m *APIKeyRepositoryMock) ListActiveByUser(userID int) ([]models.APIKey, error) {
+	args := m.Called(userID)
+	keys, _ := args.Get(0).([]models.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *APIKeyRepositoryMock) Touch(id int, lastUsedAt time.Time) error {
+	args := m.Called(id, lastUsedAt)
+	return args.Error(0)
+}
+
+func (m *APIKeyRepositoryMock) Revoke(userID, id int) (bool, error) {
+	args := m.Called(userID, id)
+	return args.Bool(0), args.Error(1)
+}
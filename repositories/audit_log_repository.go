@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type AuditLogRepository interface {
+	Create(entry *models.AuditEntry) error
+}
+
+type auditLogRepository struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+func (r *auditLogRepository) Create(entry *models.AuditEntry) error {
+	return insertAuditEntry(r.db, entry)
+}
+
+// insertAuditEntry also runs inside the transactions of other repositories,
+// so a change and its audit entry are stored together.
+func insertAuditEntry(db interface{ QueryRow(string, ...any) *sql.Row }, entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor_id, action, target_user_id, details, ip_address, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	entry.CreatedAt = time.Now().UTC()
+	err := db.QueryRow(query,
+		entry.ActorID,
+		entry.Action,
+		entry.TargetUserID,
+		entry.Details,
+		entry.IPAddress,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("error inserting audit entry: %w", err)
+	}
+	return nil
+}
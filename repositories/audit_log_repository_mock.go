@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type AuditLogRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *AuditLogRepositoryMock) Create(entry *models.AuditEntry) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type RecoveryCodeRepository interface {
+	ReplaceForUser(userID int, codeHashes []string) error
+	Consume(userID int, codeHash string) (bool, error)
+	DeleteForUser(userID int) error
+}
+
+type recoveryCodeRepository struct {
+	db *sql.DB
+}
+
+func NewRecoveryCodeRepository(db *sql.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser discards every previous recovery code of the user and stores
+// the new set in one transaction.
+func (r *recoveryCodeRepository) ReplaceForUser(userID int, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			return fmt.Errorf("error inserting recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Consume marks an unused code as used and reports whether one matched.
+func (r *recoveryCodeRepository) Consume(userID int, codeHash string) (bool, error) {
+	query := "UPDATE mfa_recovery_codes SET used_at = $3 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+
+	result, err := r.db.Exec(query, userID, codeHash, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *recoveryCodeRepository) DeleteForUser(userID int) error {
+	_, err := r.db.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID)
+	return err
+}
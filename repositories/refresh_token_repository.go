@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	FindByHash(tokenHash string) (*models.RefreshToken, error)
+	MarkRotated(id int) (bool, error)
+	RevokeFamily(familyID string) error
+}
+
+type refreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
+	query := "INSERT INTO refresh_tokens (user_id, session_id, family_id, login_method, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+
+	return r.db.QueryRow(query, token.UserID, token.SessionID, token.FamilyID, token.LoginMethod, token.TokenHash, token.ExpiresAt).Scan(&token.ID)
+}
+
+func (r *refreshTokenRepository) FindByHash(tokenHash string) (*models.RefreshToken, error) {
+	query := "SELECT id, user_id, session_id, family_id, login_method, token_hash, expires_at, rotated_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = $1"
+
+	var token models.RefreshToken
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.SessionID,
+		&token.FamilyID,
+		&token.LoginMethod,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.RotatedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error querying refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// MarkRotated flags the token as exchanged. It reports false when the token
+// was already rotated or revoked, so two concurrent refreshes cannot both win.
+func (r *refreshTokenRepository) MarkRotated(id int) (bool, error) {
+	query := "UPDATE refresh_tokens SET rotated_at = $2 WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL"
+
+	result, err := r.db.Exec(query, id, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	query := "UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL"
+
+	_, err := r.db.Exec(query, familyID, time.Now().UTC())
+	return err
+}
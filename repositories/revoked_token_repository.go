@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type RevokedTokenRepository interface {
+	Revoke(token *models.RevokedToken) error
+	ListRevokedSince(since time.Time) ([]models.RevokedToken, error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type revokedTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRevokedTokenRepository(db *sql.DB) RevokedTokenRepository {
+	return &revokedTokenRepository{db: db}
+}
+
+func (r *revokedTokenRepository) Revoke(token *models.RevokedToken) error {
+	query := "INSERT INTO revoked_tokens (jti, expires_at, revoked_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING"
+
+	_, err := r.db.Exec(query, token.JTI, token.ExpiresAt, token.RevokedAt)
+	return err
+}
+
+// ListRevokedSince returns the still-unexpired revocations recorded at or
+// after since. A zero since loads every live revocation.
+func (r *revokedTokenRepository) ListRevokedSince(since time.Time) ([]models.RevokedToken, error) {
+	query := "SELECT jti, expires_at, revoked_at FROM revoked_tokens WHERE revoked_at >= $1 AND expires_at > $2"
+
+	rows, err := r.db.Query(query, since, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error querying revoked tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.RevokedToken
+	for rows.Next() {
+		var token models.RevokedToken
+		if err := rows.Scan(&token.JTI, &token.ExpiresAt, &token.RevokedAt); err != nil {
+			return nil, fmt.Errorf("error scanning revoked token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *revokedTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	query := "DELETE FROM revoked_tokens WHERE expires_at <= $1"
+
+	result, err := r.db.Exec(query, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
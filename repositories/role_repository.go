@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type RoleRepository interface {
+	ListRolePermissions() (map[string][]string, error)
+	ListUserRoles(userID int) ([]string, error)
+	GrantRole(userID int, role string, audit *models.AuditEntry) (bool, error)
+	RevokeRole(userID int, role string, audit *models.AuditEntry) (bool, error)
+}
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// ListRolePermissions returns every role with the permissions it grants;
+// roles granting nothing map to an empty list.
+func (r *roleRepository) ListRolePermissions() (map[string][]string, error) {
+	query := `SELECT roles.name, permissions.name FROM roles
+		LEFT JOIN role_permissions ON role_permissions.role_id = roles.id
+		LEFT JOIN permissions ON permissions.id = role_permissions.permission_id
+		ORDER BY roles.name, permissions.name`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("error querying role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make(map[string][]string)
+	for rows.Next() {
+		var role string
+		var permission sql.NullString
+		if err := rows.Scan(&role, &permission); err != nil {
+			return nil, fmt.Errorf("error scanning role permission: %w", err)
+		}
+		if _, ok := roles[role]; !ok {
+			roles[role] = []string{}
+		}
+		if permission.Valid {
+			roles[role] = append(roles[role], permission.String)
+		}
+	}
+	return roles, rows.Err()
+}
+
+func (r *roleRepository) ListUserRoles(userID int) ([]string, error) {
+	query := `SELECT roles.name FROM user_roles
+		JOIN roles ON roles.id = user_roles.role_id
+		WHERE user_roles.user_id = $1 ORDER BY roles.name`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying user roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("error scanning user role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// GrantRole gives the user the role and records the audit entry in the same
+// transaction. It reports false, writing nothing, when the user already
+// holds the role.
+func (r *roleRepository) GrantRole(userID int, role string, audit *models.AuditEntry) (bool, error) {
+	query := `INSERT INTO user_roles (user_id, role_id, granted_by, granted_at)
+		SELECT $1, id, $3, $4 FROM roles WHERE name = $2
+		ON CONFLICT (user_id, role_id) DO NOTHING`
+
+	return r.changeRole(query, []any{userID, role, audit.ActorID, time.Now().UTC()}, audit)
+}
+
+// RevokeRole takes the role away and records the audit entry in the same
+// transaction. It reports false, writing nothing, when the user does not
+// hold the role.
+func (r *roleRepository) RevokeRole(userID int, role string, audit *models.AuditEntry) (bool, error) {
+	query := `DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = (SELECT id FROM roles WHERE name = $2)`
+
+	return r.changeRole(query, []any{userID, role}, audit)
+}
+
+func (r *roleRepository) changeRole(query string, args []any, audit *models.AuditEntry) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, fmt.Errorf("error changing user role: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected != 1 {
+		return false, nil
+	}
+
+	if err := insertAuditEntry(tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type RoleRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RoleRepositoryMock) ListRolePermissions() (map[string][]string, error) {
+	args := m.Called()
+	roles, _ := args.Get(0).(map[string][]string)
+	return roles, args.Error(1)
+}
+
+func (m *RoleRepositoryMock) ListUserRoles(userID int) ([]string, error) {
+	args := m.Called(userID)
+	roles, _ := args.Get(0).([]string)
+	return roles, args.Error(1)
+}
+
+func (m *RoleRepositoryMock) GrantRole(userID int, role string, audit *models.AuditEntry) (bool, error) {
+	args := m.Called(userID, role, audit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *RoleRepositoryMock) RevokeRole(userID int, role string, audit *models.AuditEntry) (bool, error) {
+	args := m.Called(userID, role, audit)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	FindByID(id int) (*models.Session, error)
+	ListActiveByUser(userID int) ([]models.Session, error)
+	Touch(id int, lastSeenAt time.Time) error
+	Revoke(id int) error
+	RevokeAllByUser(userID, exceptID int) error
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	query := "INSERT INTO sessions (user_id, device_name, user_agent, ip_address, created_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $5) RETURNING id"
+
+	session.CreatedAt = time.Now().UTC()
+	session.LastSeenAt = session.CreatedAt
+	return r.db.QueryRow(query, session.UserID, session.DeviceName, session.UserAgent, session.IPAddress, session.CreatedAt).Scan(&session.ID)
+}
+
+func (r *sessionRepository) FindByID(id int) (*models.Session, error) {
+	query := "SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_seen_at, revoked_at FROM sessions WHERE id = $1"
+
+	var session models.Session
+	err := r.db.QueryRow(query, id).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.DeviceName,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error querying session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActiveByUser(userID int) ([]models.Session, error) {
+	query := "SELECT id, user_id, device_name, user_agent, ip_address, created_at, last_seen_at, revoked_at FROM sessions WHERE user_id = $1 AND revoked_at IS NULL ORDER BY last_seen_at DESC"
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.DeviceName,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *sessionRepository) Touch(id int, lastSeenAt time.Time) error {
+	query := "UPDATE sessions SET last_seen_at = $2 WHERE id = $1"
+
+	_, err := r.db.Exec(query, id, lastSeenAt)
+	return err
+}
+
+func (r *sessionRepository) Revoke(id int) error {
+	query := "UPDATE sessions SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL"
+
+	_, err := r.db.Exec(query, id, time.Now().UTC())
+	return err
+}
+
+// RevokeAllByUser revokes every active session of the user except exceptID.
+// Pass 0 to revoke all of them.
+func (r *sessionRepository) RevokeAllByUser(userID, exceptID int) error {
+	query := "UPDATE sessions SET revoked_at = $3 WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL"
+
+	_, err := r.db.Exec(query, userID, exceptID, time.Now().UTC())
+	return err
+}
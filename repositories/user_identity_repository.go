@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type UserIdentityRepository interface {
+	Create(identity *models.UserIdentity) error
+	FindByIssuerSubject(issuer, subject string) (*models.UserIdentity, error)
+	RecordLogin(id int) error
+}
+
+type userIdentityRepository struct {
+	db *sql.DB
+}
+
+func NewUserIdentityRepository(db *sql.DB) UserIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (r *userIdentityRepository) Create(identity *models.UserIdentity) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, $5) RETURNING id`
+
+	identity.CreatedAt = time.Now().UTC()
+	identity.LastLoginAt = sql.NullTime{Time: identity.CreatedAt, Valid: true}
+	return r.db.QueryRow(query,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+	).Scan(&identity.ID)
+}
+
+func (r *userIdentityRepository) FindByIssuerSubject(issuer, subject string) (*models.UserIdentity, error) {
+	query := `SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM user_identities WHERE issuer = $1 AND subject = $2`
+
+	var identity models.UserIdentity
+	err := r.db.QueryRow(query, issuer, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error querying user identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+func (r *userIdentityRepository) RecordLogin(id int) error {
+	query := "UPDATE user_identities SET last_login_at = $2 WHERE id = $1"
+
+	_, err := r.db.Exec(query, id, time.Now().UTC())
+	return err
+}
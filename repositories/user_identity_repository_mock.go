@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type UserIdentityRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *UserIdentityRepositoryMock) Create(identity *models.UserIdentity) error {
+	args := m.Called(identity)
+	return args.Error(0)
+}
+
+func (m *UserIdentityRepositoryMock) FindByIssuerSubject(issuer, subject string) (*models.UserIdentity, error) {
+	args := m.Called(issuer, subject)
+	identity, _ := args.Get(0).(*models.UserIdentity)
+	return identity, args.Error(1)
+}
+
+func (m *UserIdentityRepositoryMock) RecordLogin(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -5,14 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"go-tutuplapak-user/models"
+	"time"
 )
 
 type UserRepository interface {
+	FindByID(id int) (*models.User, error)
 	FindByEmail(email string) (*models.User, error)
 	FindByPhone(phone string) (*models.User, error)
 	EmailExists(email string) (bool, error)
 	PhoneExists(phone string) (bool, error)
 	CreateUser(user *models.User) error
+	SetTOTPSecret(id int, secret string) error
+	EnableTOTP(id int) error
+	DisableTOTP(id int) error
+	UseTOTPStep(id int, step int64) (bool, error)
+	MarkEmailVerified(id int, email string) (bool, error)
+	MarkPhoneVerified(id int, phone string) (bool, error)
+	UpdatePassword(id int, passwordHash string) error
+	RehashPassword(id int, oldHash, newHash string) error
+	RecordLoginFailure(id int) (int, error)
+	LockUntil(id int, until time.Time) error
+	ResetLoginFailures(id int) error
 }
 
 type userRepository struct {
@@ -23,15 +36,35 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) FindByEmail(email string) (*models.User, error) {
-	query := "SELECT id, email, phone, password FROM users WHERE email = $1"
+// userColumns is the column list every user lookup selects, in the order
+// scanUser expects.
+const userColumns = `id, email, phone, password, file_id, file_uri, file_thumbnail_uri,
+	bank_account_name, bank_account_holder, bank_account_number, email_verified_at,
+	phone_verified_at, password_changed_at, failed_login_attempts, locked_until, totp_secret, totp_enabled_at, totp_last_step, created_at, updated_at`
 
+func scanUser(row *sql.Row) (*models.User, error) {
 	var user models.User
-	err := r.db.QueryRow(query, email).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.Email,
 		&user.Phone,
 		&user.Password,
+		&user.FileID,
+		&user.FileURI,
+		&user.FileThumbnailURI,
+		&user.BankAccountName,
+		&user.BankAccountHolder,
+		&user.BankAccountNumber,
+		&user.EmailVerifiedAt,
+		&user.PhoneVerifiedAt,
+		&user.PasswordChangedAt,
+		&user.FailedLogins,
+		&user.LockedUntil,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+		&user.TOTPLastStep,
+		&user.CreatedAt,
+		&user.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -43,20 +76,19 @@ func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) FindByPhone(phone string) (*models.User, error) {
-	query := "SELECT id, email, phone, password FROM users WHERE phone = $1"
-	row := r.db.QueryRow(query, phone)
-	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.Phone, &user.Password)
+func (r *userRepository) FindByID(id int) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+	return scanUser(r.db.QueryRow(query, id))
+}
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("error querying user: %w", err)
-	}
+func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
+	return scanUser(r.db.QueryRow(query, email))
+}
 
-	return &user, nil
+func (r *userRepository) FindByPhone(phone string) (*models.User, error) {
+	query := "SELECT " + userColumns + " FROM users WHERE phone = $1"
+	return scanUser(r.db.QueryRow(query, phone))
 }
 
 func (r *userRepository) EmailExists(email string) (bool, error) {
@@ -78,8 +110,121 @@ func (r *userRepository) PhoneExists(phone string) (bool, error) {
 }
 
 func (r *userRepository) CreateUser(user *models.User) error {
-	query := "INSERT INTO users (email, phone, password, bank_account_name, bank_account_holder, bank_account_number) VALUES ($1, $2, $3, $4, $5, $6)"
+	query := "INSERT INTO users (email, phone, password, bank_account_name, bank_account_holder, bank_account_number) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+
+	return r.db.QueryRow(query, user.Email, user.Phone, user.Password, user.BankAccountName, user.BankAccountHolder, user.BankAccountNumber).Scan(&user.ID)
+}
+
+// UpdatePassword replaces the password hash and records when it changed, which
+// invalidates every token issued before.
+func (r *userRepository) UpdatePassword(id int, passwordHash string) error {
+	query := "UPDATE users SET password = $2, password_changed_at = $3, updated_at = $3 WHERE id = $1"
+
+	_, err := r.db.Exec(query, id, passwordHash, time.Now().UTC())
+	return err
+}
+
+// RehashPassword upgrades the stored hash of an unchanged password. Unlike
+// UpdatePassword it keeps existing tokens valid, and it does nothing if the
+// password was changed in the meantime.
+func (r *userRepository) RehashPassword(id int, oldHash, newHash string) error {
+	query := "UPDATE users SET password = $3, updated_at = $4 WHERE id = $1 AND password = $2"
+
+	_, err := r.db.Exec(query, id, oldHash, newHash, time.Now().UTC())
+	return err
+}
+
+// RecordLoginFailure counts a wrong password and returns the number of
+// consecutive failures including this one.
+func (r *userRepository) RecordLoginFailure(id int) (int, error) {
+	query := "UPDATE users SET failed_login_attempts = failed_login_attempts + 1 WHERE id = $1 RETURNING failed_login_attempts"
+
+	var failures int
+	err := r.db.QueryRow(query, id).Scan(&failures)
+	return failures, err
+}
+
+func (r *userRepository) LockUntil(id int, until time.Time) error {
+	query := "UPDATE users SET locked_until = $2 WHERE id = $1"
+
+	_, err := r.db.Exec(query, id, until.UTC())
+	return err
+}
+
+// ResetLoginFailures clears the failure counter and lifts any lock.
+func (r *userRepository) ResetLoginFailures(id int) error {
+	query := "UPDATE users SET failed_login_attempts = 0, locked_until = NULL WHERE id = $1"
+
+	_, err := r.db.Exec(query, id)
+	return err
+}
+
+// SetTOTPSecret stores a pending secret; it only takes effect after EnableTOTP.
+func (r *userRepository) SetTOTPSecret(id int, secret string) error {
+	query := "UPDATE users SET totp_secret = $2, totp_enabled_at = NULL, totp_last_step = 0, updated_at = $3 WHERE id = $1"
 
-	_, err := r.db.Exec(query, user.Email, user.Phone, user.Password, user.BankAccountName, user.BankAccountHolder, user.BankAccountNumber)
+	_, err := r.db.Exec(query, id, secret, time.Now().UTC())
 	return err
 }
+
+func (r *userRepository) EnableTOTP(id int) error {
+	query := "UPDATE users SET totp_enabled_at = $2, updated_at = $2 WHERE id = $1 AND totp_secret IS NOT NULL"
+
+	_, err := r.db.Exec(query, id, time.Now().UTC())
+	return err
+}
+
+func (r *userRepository) DisableTOTP(id int) error {
+	query := "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0, updated_at = $2 WHERE id = $1"
+
+	_, err := r.db.Exec(query, id, time.Now().UTC())
+	return err
+}
+
+// UseTOTPStep records the time step of an accepted code. It reports false
+// when that step or a later one was already used, which rejects replays.
+func (r *userRepository) UseTOTPStep(id int, step int64) (bool, error) {
+	query := "UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2"
+
+	result, err := r.db.Exec(query, id, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// MarkEmailVerified flags the address as verified, provided it is still the
+// user's current email.
+func (r *userRepository) MarkEmailVerified(id int, email string) (bool, error) {
+	query := "UPDATE users SET email_verified_at = $3, updated_at = $3 WHERE id = $1 AND email = $2"
+
+	result, err := r.db.Exec(query, id, email, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// MarkPhoneVerified flags the number as verified, provided it is still the
+// user's current phone.
+func (r *userRepository) MarkPhoneVerified(id int, phone string) (bool, error) {
+	query := "UPDATE users SET phone_verified_at = $3, updated_at = $3 WHERE id = $1 AND phone = $2"
+
+	result, err := r.db.Exec(query, id, phone, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
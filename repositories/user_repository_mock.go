@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type UserRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *UserRepositoryMock) FindByID(id int) (*models.User, error) {
+	args := m.Called(id)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *UserRepositoryMock) FindByEmail(email string) (*models.User, error) {
+	args := m.Called(email)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *UserRepositoryMock) FindByPhone(phone string) (*models.User, error) {
+	args := m.Called(phone)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *UserRepositoryMock) EmailExists(email string) (bool, error) {
+	args := m.Called(email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepositoryMock) PhoneExists(phone string) (bool, error) {
+	args := m.Called(phone)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepositoryMock) CreateUser(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) SetTOTPSecret(id int, secret string) error {
+	args := m.Called(id, secret)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) EnableTOTP(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) DisableTOTP(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) UseTOTPStep(id int, step int64) (bool, error) {
+	args := m.Called(id, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepositoryMock) MarkEmailVerified(id int, email string) (bool, error) {
+	args := m.Called(id, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepositoryMock) MarkPhoneVerified(id int, phone string) (bool, error) {
+	args := m.Called(id, phone)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepositoryMock) UpdatePassword(id int, passwordHash string) error {
+	args := m.Called(id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) RecordLoginFailure(id int) (int, error) {
+	args := m.Called(id)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *UserRepositoryMock) LockUntil(id int, until time.Time) error {
+	args := m.Called(id, until)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) ResetLoginFailures(id int) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *UserRepositoryMock) RehashPassword(id int, oldHash, newHash string) error {
+	args := m.Called(id, oldHash, newHash)
+	return args.Error(0)
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type VerificationCodeRepository interface {
+	Create(code *models.VerificationCode) error
+	Consume(purpose, codeHash string) (*models.VerificationCode, error)
+	FindLatestActive(purpose, target string) (*models.VerificationCode, error)
+	RecordAttempt(id, maxAttempts int) (bool, error)
+	ConsumeByID(id int) (bool, error)
+	SentSince(purpose, target string, since time.Time) ([]time.Time, error)
+}
+
+type verificationCodeRepository struct {
+	db *sql.DB
+}
+
+func NewVerificationCodeRepository(db *sql.DB) VerificationCodeRepository {
+	return &verificationCodeRepository{db: db}
+}
+
+func (r *verificationCodeRepository) Create(code *models.VerificationCode) error {
+	query := "INSERT INTO verification_codes (user_id, purpose, target, code_hash, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+
+	code.CreatedAt = time.Now().UTC()
+	return r.db.QueryRow(query, code.UserID, code.Purpose, code.Target, code.CodeHash, code.ExpiresAt, code.CreatedAt).Scan(&code.ID)
+}
+
+// Consume atomically marks an unexpired, unused code as used and returns it,
+// or nil when no such code exists.
+func (r *verificationCodeRepository) Consume(purpose, codeHash string) (*models.VerificationCode, error) {
+	query := `UPDATE verification_codes SET consumed_at = $3
+		WHERE purpose = $1 AND code_hash = $2 AND consumed_at IS NULL AND expires_at > $3
+		RETURNING id, user_id, purpose, target, code_hash, attempts, expires_at, consumed_at, created_at`
+
+	code, err := scanVerificationCode(r.db.QueryRow(query, purpose, codeHash, time.Now().UTC()))
+	if err != nil {
+		return nil, fmt.Errorf("error consuming verification code: %w", err)
+	}
+	return code, nil
+}
+
+// FindLatestActive returns the newest unexpired, unused code sent to the
+// target. Requesting a new code therefore supersedes older ones.
+func (r *verificationCodeRepository) FindLatestActive(purpose, target string) (*models.VerificationCode, error) {
+	query := `SELECT id, user_id, purpose, target, code_hash, attempts, expires_at, consumed_at, created_at
+		FROM verification_codes
+		WHERE purpose = $1 AND target = $2 AND consumed_at IS NULL AND expires_at > $3
+		ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	code, err := scanVerificationCode(r.db.QueryRow(query, purpose, target, time.Now().UTC()))
+	if err != nil {
+		return nil, fmt.Errorf("error finding verification code: %w", err)
+	}
+	return code, nil
+}
+
+// RecordAttempt counts a guess against the code and reports whether it was
+// still within maxAttempts. The guess must only be checked when it was.
+func (r *verificationCodeRepository) RecordAttempt(id, maxAttempts int) (bool, error) {
+	query := "UPDATE verification_codes SET attempts = attempts + 1 WHERE id = $1 AND attempts < $2"
+
+	result, err := r.db.Exec(query, id, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ConsumeByID marks the code as used, reporting false if it already was.
+func (r *verificationCodeRepository) ConsumeByID(id int) (bool, error) {
+	query := "UPDATE verification_codes SET consumed_at = $2 WHERE id = $1 AND consumed_at IS NULL"
+
+	result, err := r.db.Exec(query, id, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// SentSince returns when codes were issued to the target after since, oldest
+// first, for enforcing resend limits.
+func (r *verificationCodeRepository) SentSince(purpose, target string, since time.Time) ([]time.Time, error) {
+	query := "SELECT created_at FROM verification_codes WHERE purpose = $1 AND target = $2 AND created_at > $3 ORDER BY created_at"
+
+	rows, err := r.db.Query(query, purpose, target, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error listing verification codes: %w", err)
+	}
+	defer rows.Close()
+
+	var sent []time.Time
+	for rows.Next() {
+		var createdAt time.Time
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning verification code: %w", err)
+		}
+		sent = append(sent, createdAt)
+	}
+	return sent, rows.Err()
+}
+
+func scanVerificationCode(row *sql.Row) (*models.VerificationCode, error) {
+	var code models.VerificationCode
+	err := row.Scan(
+		&code.ID,
+		&code.UserID,
+		&code.Purpose,
+		&code.Target,
+		&code.CodeHash,
+		&code.Attempts,
+		&code.ExpiresAt,
+		&code.ConsumedAt,
+		&code.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
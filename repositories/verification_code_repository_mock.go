@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type VerificationCodeRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *VerificationCodeRepositoryMock) Create(code *models.VerificationCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *VerificationCodeRepositoryMock) Consume(purpose, codeHash string) (*models.VerificationCode, error) {
+	args := m.Called(purpose, codeHash)
+	code, _ := args.Get(0).(*models.VerificationCode)
+	return code, args.Error(1)
+}
+
+func (m *VerificationCodeRepositoryMock) FindLatestActive(purpose, target string) (*models.VerificationCode, error) {
+	args := m.Called(purpose, target)
+	code, _ := args.Get(0).(*models.VerificationCode)
+	return code, args.Error(1)
+}
+
+func (m *VerificationCodeRepositoryMock) RecordAttempt(id, maxAttempts int) (bool, error) {
+	args := m.Called(id, maxAttempts)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *VerificationCodeRepositoryMock) ConsumeByID(id int) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *VerificationCodeRepositoryMock) SentSince(purpose, target string, since time.Time) ([]time.Time, error) {
+	args := m.Called(purpose, target, since)
+	sent, _ := args.Get(0).([]time.Time)
+	return sent, args.Error(1)
+}
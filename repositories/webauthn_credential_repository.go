@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"time"
+)
+
+type WebAuthnCredentialRepository interface {
+	Create(credential *models.WebAuthnCredential) error
+	FindByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error)
+	ListByUser(userID int) ([]models.WebAuthnCredential, error)
+	UpdateSignCount(id int, signCount uint32) (bool, error)
+}
+
+type webAuthnCredentialRepository struct {
+	db *sql.DB
+}
+
+func NewWebAuthnCredentialRepository(db *sql.DB) WebAuthnCredentialRepository {
+	return &webAuthnCredentialRepository{db: db}
+}
+
+func (r *webAuthnCredentialRepository) Create(credential *models.WebAuthnCredential) error {
+	query := `INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, aaguid, transports, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	credential.CreatedAt = time.Now().UTC()
+	return r.db.QueryRow(query,
+		credential.UserID,
+		credential.CredentialID,
+		credential.PublicKey,
+		int64(credential.SignCount),
+		credential.AAGUID,
+		credential.Transports,
+		credential.CreatedAt,
+	).Scan(&credential.ID)
+}
+
+func (r *webAuthnCredentialRepository) FindByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, credential_id, public_key, sign_count, aaguid, transports, created_at, last_used_at
+		FROM webauthn_credentials WHERE credential_id = $1`
+
+	var credential models.WebAuthnCredential
+	err := scanWebAuthnCredential(r.db.QueryRow(query, credentialID), &credential)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error querying webauthn credential: %w", err)
+	}
+
+	return &credential, nil
+}
+
+func (r *webAuthnCredentialRepository) ListByUser(userID int) ([]models.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, credential_id, public_key, sign_count, aaguid, transports, created_at, last_used_at
+		FROM webauthn_credentials WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webauthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	credentials := []models.WebAuthnCredential{}
+	for rows.Next() {
+		var credential models.WebAuthnCredential
+		if err := scanWebAuthnCredential(rows, &credential); err != nil {
+			return nil, fmt.Errorf("error scanning webauthn credential: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// UpdateSignCount records a login with the credential. It reports false when
+// another login already stored a counter at least as high, so two requests
+// replaying the same assertion cannot both succeed. Authenticators without a
+// counter always send zero.
+func (r *webAuthnCredentialRepository) UpdateSignCount(id int, signCount uint32) (bool, error) {
+	query := "UPDATE webauthn_credentials SET sign_count = $2, last_used_at = $3 WHERE id = $1 AND (sign_count < $2 OR $2 = 0)"
+
+	result, err := r.db.Exec(query, id, int64(signCount), time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func scanWebAuthnCredential(row interface{ Scan(...any) error }, credential *models.WebAuthnCredential) error {
+	return row.Scan(
+		&credential.ID,
+		&credential.UserID,
+		&credential.CredentialID,
+		&credential.PublicKey,
+		&credential.SignCount,
+		&credential.AAGUID,
+		&credential.Transports,
+		&credential.CreatedAt,
+		&credential.LastUsedAt,
+	)
+}
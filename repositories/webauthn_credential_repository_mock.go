@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WebAuthnCredentialRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *WebAuthnCredentialRepositoryMock) Create(credential *models.WebAuthnCredential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *WebAuthnCredentialRepositoryMock) FindByCredentialID(credentialID []byte) (*models.WebAuthnCredential, error) {
+	args := m.Called(credentialID)
+	credential, _ := args.Get(0).(*models.WebAuthnCredential)
+	return credential, args.Error(1)
+}
+
+func (m *WebAuthnCredentialRepositoryMock) ListByUser(userID int) ([]models.WebAuthnCredential, error) {
+	args := m.Called(userID)
+	credentials, _ := args.Get(0).([]models.WebAuthnCredential)
+	return credentials, args.Error(1)
+}
+
+func (m *WebAuthnCredentialRepositoryMock) UpdateSignCount(id int, signCount uint32) (bool, error) {
+	args := m.Called(id, signCount)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes an API key can be granted. Routes that manage credentials or
+// sessions are not covered by any scope and only accept user logins.
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeSessionsRead = "sessions:read"
+)
+
+// APIKeyScopes lists every scope. Access tokens are issued with all of them,
+// since a logged in user may do anything a key can.
+var APIKeyScopes = []string{ScopeProfileRead, ScopeSessionsRead}
+
+// apiKeyPrefix starts every key so leaked keys are easy to scan for.
+const apiKeyPrefix = "tlk_"
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid or expired api key")
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrUnknownScope      = errors.New("unknown scope")
+	ErrAPIKeyExpiry      = errors.New("expiry must be in the future")
+	ErrTooManyAPIKeys    = errors.New("too many api keys, revoke one first")
+	ErrInsufficientScope = errors.New("api key does not grant this scope")
+)
+
+type APIKeyService interface {
+	CreateKey(user *models.User, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error)
+	ListKeys(userID int) ([]models.APIKey, error)
+	RevokeKey(userID, id int) error
+	Authenticate(rawKey string) (*models.User, *models.APIKey, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repositories.APIKeyRepository
+	userRepo   repositories.UserRepository
+	cfg        config.Config
+}
+
+func NewAPIKeyService(apiKeyRepo repositories.APIKeyRepository, userRepo repositories.UserRepository, cfg config.Config) APIKeyService {
+	return &apiKeyService{apiKeyRepo: apiKeyRepo, userRepo: userRepo, cfg: cfg}
+}
+
+// CreateKey issues a key acting as the user. The key is returned once, as
+// "tlk_<prefix>_<secret>"; only the prefix and a hash of the secret are
+// stored.
+func (s *apiKeyService) CreateKey(user *models.User, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, "", fmt.Errorf("%w %q", ErrUnknownScope, scope)
+		}
+	}
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+
+	key := &models.APIKey{
+		UserID: user.ID,
+		Name:   name,
+		Scopes: strings.Join(scopes, " "),
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, "", ErrAPIKeyExpiry
+		}
+		key.ExpiresAt.Time, key.ExpiresAt.Valid = expiresAt.UTC(), true
+	}
+
+	existing, err := s.apiKeyRepo.ListActiveByUser(user.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if len(existing) >= s.cfg.APIKeyMaxPerUser {
+		return nil, "", ErrTooManyAPIKeys
+	}
+
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	key.Prefix = hex.EncodeToString(prefix)
+	key.SecretHash = utils.HashToken(secret)
+
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return key, apiKeyPrefix + key.Prefix + "_" + secret, nil
+}
+
+func (s *apiKeyService) ListKeys(userID int) ([]models.APIKey, error) {
+	keys, err := s.apiKeyRepo.ListActiveByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) RevokeKey(userID, id int) error {
+	revoked, err := s.apiKeyRepo.Revoke(userID, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a key sent in an "Authorization: ApiKey" header to
+// the user it acts as.
+func (s *apiKeyService) Authenticate(rawKey string) (*models.User, *models.APIKey, error) {
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(rawKey, apiKeyPrefix), "_")
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) || !found || prefix == "" || secret == "" {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.FindByPrefix(prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	if key.RevokedAt.Valid || (key.ExpiresAt.Valid && !now.Before(key.ExpiresAt.Time)) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.FindByID(key.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	if !key.LastUsedAt.Valid || now.Sub(key.LastUsedAt.Time) > lastSeenResolution {
+		if err := s.apiKeyRepo.Touch(key.ID, now); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+		key.LastUsedAt.Time, key.LastUsedAt.Valid = now, true
+	}
+
+	return user, key, nil
+}
+
+// APIKeyHasScope tells whether the key was granted the scope.
+func APIKeyHasScope(key *models.APIKey, scope string) bool {
+	return slices.Contains(strings.Fields(key.Scopes), scope)
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type APIKeyServiceMock struct {
+	mock.Mock
+}
+
+func (m *APIKeyServiceMock) CreateKey(user *models.User, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	args := m.Called(user, name, scopes, expiresAt)
+	key, _ := args.Get(0).(*models.APIKey)
+	return key, args.String(1), args.Error(2)
+}
+
+func (m *APIKeyServiceMock) ListKeys(userID int) ([]models.APIKey, error) {
+	args := m.Called(userID)
+	keys, _ := args.Get(0).([]models.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *APIKeyServiceMock) RevokeKey(userID, id int) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *APIKeyServiceMock) Authenticate(rawKey string) (*models.User, *models.APIKey, error) {
+	args := m.Called(rawKey)
+	user, _ := args.Get(0).(*models.User)
+	key, _ := args.Get(1).(*models.APIKey)
+	return user, key, args.Error(2)
+}
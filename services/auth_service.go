@@ -8,80 +8,151 @@ import (
 	"go-tutuplapak-user/models"
 	"go-tutuplapak-user/repositories"
 	"go-tutuplapak-user/utils"
+	"log"
+	"time"
 )
 
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+
+	// ErrRegistrationPending answers every registration when
+	// HardenedAuthErrors is on. It is not a failure: the account was created,
+	// or its owner was told about the attempt, and the rest happens through
+	// the message sent.
+	ErrRegistrationPending = errors.New("we sent you a message to finish signing up")
+)
+
+// AccountLockedError is returned by the password logins and the second login
+// step while the account is locked after too many wrong passwords or codes.
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return "account is temporarily locked after too many failed logins"
+}
+
 type AuthService interface {
-	LoginWithEmail(email, password string) (*models.User, string, error)
-	LoginWithPhone(phone, password string) (*models.User, string, error)
-	RegisterWithEmail(email, password string) (*models.User, string, error)
-	RegisterWithPhone(phone, password string) (*models.User, string, error)
+	Login(identifier, password string, client ClientInfo) (*models.User, *TokenPair, error)
+	LoginWithEmail(email, password string, client ClientInfo) (*models.User, *TokenPair, error)
+	LoginWithPhone(phone, password string, client ClientInfo) (*models.User, *TokenPair, error)
+	RegisterWithEmail(email, password string, client ClientInfo) (*models.User, *TokenPair, error)
+	RegisterWithPhone(phone, password string, client ClientInfo) (*models.User, *TokenPair, error)
+	UnlockUser(userID int) error
 }
 
 type authService struct {
-	userRepo repositories.UserRepository
-	cfg      config.Config
+	userRepo            repositories.UserRepository
+	tokenService        TokenService
+	verificationService VerificationService
+	policy              *utils.PasswordPolicy
+	cfg                 config.Config
+}
+
+func NewAuthService(userRepo repositories.UserRepository, tokenService TokenService, verificationService VerificationService, policy *utils.PasswordPolicy, cfg config.Config) AuthService {
+	return &authService{
+		userRepo:            userRepo,
+		tokenService:        tokenService,
+		verificationService: verificationService,
+		policy:              policy,
+		cfg:                 cfg,
+	}
 }
 
-func NewAuthService(userRepo repositories.UserRepository, cfg config.Config) AuthService {
-	return &authService{userRepo: userRepo, cfg: cfg}
+// Login signs a user in with whatever they typed into a single identifier
+// field, an email address or a phone number.
+func (s *authService) Login(identifier, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	kind, normalized, ok := utils.ClassifyIdentifier(identifier)
+	if !ok {
+		return nil, nil, ErrInvalidIdentifier
+	}
+
+	if kind == utils.IdentifierEmail {
+		return s.LoginWithEmail(normalized, password, client)
+	}
+	return s.LoginWithPhone(normalized, password, client)
 }
 
-func (s *authService) LoginWithEmail(email, password string) (*models.User, string, error) {
+func (s *authService) LoginWithEmail(email, password string, client ClientInfo) (*models.User, *TokenPair, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
 	}
 
 	if user == nil {
-		return nil, "", errors.New("email not found")
+		return nil, nil, s.missingAccount(password, errors.New("email not found"))
 	}
 
-	if !utils.CheckPasswordHash(password, user.Password) {
-		return nil, "", errors.New("invalid password")
+	if err := s.checkPassword(user, password); err != nil {
+		return nil, nil, s.hideLoginFailure(err)
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		return nil, nil, s.requireMFA(user, LoginMethodEmail)
 	}
 
-	token, err := utils.GenerateJWT(email, s.cfg.JWTSecret, s.cfg.JWTExpiryHours)
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodEmail, client)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (s *authService) LoginWithPhone(phone, password string) (*models.User, string, error) {
+func (s *authService) LoginWithPhone(phone, password string, client ClientInfo) (*models.User, *TokenPair, error) {
 	user, err := s.userRepo.FindByPhone(phone)
 	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
 	}
 
 	if user == nil {
-		return nil, "", errors.New("phone not found")
+		return nil, nil, s.missingAccount(password, errors.New("phone not found"))
 	}
 
-	if !utils.CheckPasswordHash(password, user.Password) {
-		return nil, "", errors.New("invalid password")
+	if err := s.checkPassword(user, password); err != nil {
+		return nil, nil, s.hideLoginFailure(err)
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		return nil, nil, s.requireMFA(user, LoginMethodPhone)
 	}
 
-	token, err := utils.GenerateJWT(phone, s.cfg.JWTSecret, s.cfg.JWTExpiryHours)
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodPhone, client)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (s *authService) RegisterWithEmail(email, password string) (*models.User, string, error) {
-	exists, err := s.userRepo.FindByEmail(email)
-	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
-	}
-	if exists != nil {
-		return nil, "", errors.New("email already exists")
+// RegisterWithEmail creates an account and signs it in. With
+// HardenedAuthErrors it returns ErrRegistrationPending instead, for new and
+// taken addresses alike, and the mail sent tells them apart.
+func (s *authService) RegisterWithEmail(email, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	// The policy check and the hash come first so a taken address is neither
+	// answered differently nor faster.
+	if err := checkPasswordPolicy(s.policy, password, email); err != nil {
+		return nil, nil, err
 	}
 
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	exists, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if exists != nil {
+		if s.cfg.HardenedAuthErrors {
+			s.inBackground(exists, func() error {
+				return s.verificationService.SendAccountExistsNotice(exists, LoginMethodEmail)
+			})
+			return nil, nil, ErrRegistrationPending
+		}
+		return nil, nil, errors.New("email already exists")
 	}
 
 	user := &models.User{
@@ -90,34 +161,59 @@ func (s *authService) RegisterWithEmail(email, password string) (*models.User, s
 	}
 
 	if err := s.userRepo.CreateUser(user); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	if s.cfg.HardenedAuthErrors {
+		s.inBackground(user, func() error {
+			return s.verificationService.SendEmailVerification(user)
+		})
+		return nil, nil, ErrRegistrationPending
+	}
+
+	// The account is usable right away, so a mail failure must not fail the
+	// registration; the user can ask for a new link later.
+	if err := s.verificationService.SendEmailVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
 	}
 
-	token, err := utils.GenerateJWT(email, s.cfg.JWTSecret, s.cfg.JWTExpiryHours)
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodEmail, client)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	return user, token, nil
+	return user, tokens, nil
 }
 
-func (s *authService) RegisterWithPhone(phone, password string) (*models.User, string, error) {
+// RegisterWithPhone works like RegisterWithEmail, with a text message in
+// place of the mail.
+func (s *authService) RegisterWithPhone(phone, password string, client ClientInfo) (*models.User, *TokenPair, error) {
 
 	if !utils.IsValidPhoneNumber(phone) {
-		return nil, "", errors.New("phone number must start with '+' and be followed by digits")
+		return nil, nil, errors.New("phone number must start with '+' and be followed by digits")
 	}
 
-	exists, err := s.userRepo.FindByPhone(phone)
-	if err != nil {
-		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
-	}
-	if exists != nil {
-		return nil, "", errors.New("phone already exists")
+	if err := checkPasswordPolicy(s.policy, password, phone); err != nil {
+		return nil, nil, err
 	}
 
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	exists, err := s.userRepo.FindByPhone(phone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if exists != nil {
+		if s.cfg.HardenedAuthErrors {
+			s.inBackground(exists, func() error {
+				return s.verificationService.SendAccountExistsNotice(exists, LoginMethodPhone)
+			})
+			return nil, nil, ErrRegistrationPending
+		}
+		return nil, nil, errors.New("phone already exists")
 	}
 
 	user := &models.User{
@@ -126,13 +222,164 @@ func (s *authService) RegisterWithPhone(phone, password string) (*models.User, s
 	}
 
 	if err := s.userRepo.CreateUser(user); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	if s.cfg.HardenedAuthErrors {
+		s.inBackground(user, func() error {
+			return s.verificationService.SendPhoneVerification(user)
+		})
+		return nil, nil, ErrRegistrationPending
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodPhone, client)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	token, err := utils.GenerateJWT(phone, s.cfg.JWTSecret, s.cfg.JWTExpiryHours)
+	return user, tokens, nil
+}
+
+// UnlockUser lifts a lockout before it expires, e.g. after support confirmed
+// the owner's identity.
+func (s *authService) UnlockUser(userID int) error {
+	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return nil, "", err
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
+
+// checkPassword verifies a login password and enforces the lockout. Locked
+// accounts are refused without looking at the password, and once the
+// threshold is reached every further wrong password doubles the lock.
+func (s *authService) checkPassword(user *models.User, password string) error {
+	now := time.Now().UTC()
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(now) {
+		if s.cfg.HardenedAuthErrors {
+			// Take as long as a wrong password would.
+			utils.CheckPasswordHash(password, user.Password)
+		}
+		return &AccountLockedError{RetryAfter: user.LockedUntil.Time.Sub(now)}
+	}
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		if err := recordLoginFailure(s.userRepo, s.cfg, user.ID, now); err != nil {
+			return err
+		}
+		return errors.New("invalid password")
+	}
+
+	// With two-factor authentication the counter is only cleared once the
+	// code is right too, so wrong codes keep adding to it.
+	if (user.FailedLogins > 0 || user.LockedUntil.Valid) && !user.TOTPEnabledAt.Valid {
+		if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+			return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+	}
+
+	s.rehashPassword(user, password)
+	return nil
+}
+
+// missingAccount answers a password login for an account that does not
+// exist. In hardened mode it spends the time of a password check and returns
+// the same error as a wrong password.
+func (s *authService) missingAccount(password string, err error) error {
+	if !s.cfg.HardenedAuthErrors {
+		return err
+	}
+	utils.CheckDummyPasswordHash(password)
+	return ErrInvalidCredentials
+}
+
+// hideLoginFailure replaces the reason a password check failed with
+// ErrInvalidCredentials in hardened mode, so a locked account looks like a
+// wrong password. Internal errors are passed on.
+func (s *authService) hideLoginFailure(err error) error {
+	if !s.cfg.HardenedAuthErrors || errors.Is(err, utils.ErrInternal) {
+		return err
 	}
+	return ErrInvalidCredentials
+}
 
-	return user, token, nil
+// inBackground runs a send for a registration answered with
+// ErrRegistrationPending, so its duration does not show which mail went out.
+func (s *authService) inBackground(user *models.User, send func() error) {
+	go func() {
+		if err := send(); err != nil {
+			log.Printf("Failed to send registration message to user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+// rehashPassword moves a hash made with an older algorithm or parameters to
+// the current ones while the plain password is at hand. Failures only cost
+// another attempt on the next login, so they are logged and ignored.
+func (s *authService) rehashPassword(user *models.User, password string) {
+	if !utils.PasswordNeedsRehash(user.Password) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password of user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := s.userRepo.RehashPassword(user.ID, user.Password, hashedPassword); err != nil {
+		log.Printf("Failed to store rehashed password of user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
+
+// recordLoginFailure counts a wrong password or second-factor code against
+// the user and locks the account once LockoutThreshold is reached, returning
+// an AccountLockedError.
+func recordLoginFailure(userRepo repositories.UserRepository, cfg config.Config, userID int, now time.Time) error {
+	failures, err := userRepo.RecordLoginFailure(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if failures < cfg.LockoutThreshold {
+		return nil
+	}
+
+	lock := lockoutDuration(cfg, failures-cfg.LockoutThreshold)
+	if err := userRepo.LockUntil(userID, now.Add(lock)); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return &AccountLockedError{RetryAfter: lock}
+}
+
+// lockoutDuration returns the base lock doubled once per failure past the
+// threshold, capped at the configured maximum.
+func lockoutDuration(cfg config.Config, extraFailures int) time.Duration {
+	lock := time.Second * time.Duration(cfg.LockoutBaseSeconds)
+	limit := time.Second * time.Duration(cfg.LockoutMaxSeconds)
+	for i := 0; i < extraFailures && lock < limit; i++ {
+		lock *= 2
+	}
+	if lock > limit {
+		return limit
+	}
+	return lock
+}
+
+// requireMFA stops a password login for users with two-factor authentication
+// and hands out the token for the second step instead.
+func (s *authService) requireMFA(user *models.User, loginMethod string) error {
+	mfaToken, err := s.tokenService.IssueMFAToken(user, loginMethod)
+	if err != nil {
+		return err
+	}
+	return &MFARequiredError{MFAToken: mfaToken}
 }
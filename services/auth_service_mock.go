@@ -10,30 +10,42 @@ type AuthServiceMock struct {
 	mock.Mock
 }
 
-func (m *AuthServiceMock) LoginWithEmail(email, password string) (*models.User, string, error) {
-	args := m.Called(email, password)
+func (m *AuthServiceMock) Login(identifier, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(identifier, password, client)
 	user, _ := args.Get(0).(*models.User)
-	token, _ := args.Get(1).(string)
-	return user, token, args.Error(2)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
 }
 
-func (m *AuthServiceMock) LoginWithPhone(phone, password string) (*models.User, string, error) {
-	args := m.Called(phone, password)
+func (m *AuthServiceMock) LoginWithEmail(email, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(email, password, client)
 	user, _ := args.Get(0).(*models.User)
-	token, _ := args.Get(1).(string)
-	return user, token, args.Error(2)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
 }
 
-func (m *AuthServiceMock) RegisterWithEmail(email, password string) (*models.User, string, error) {
-	args := m.Called(email, password)
+func (m *AuthServiceMock) LoginWithPhone(phone, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(phone, password, client)
 	user, _ := args.Get(0).(*models.User)
-	token, _ := args.Get(1).(string)
-	return user, token, args.Error(2)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
 }
 
-func (m *AuthServiceMock) RegisterWithPhone(phone, password string) (*models.User, string, error) {
-	args := m.Called(phone, password)
+func (m *AuthServiceMock) RegisterWithEmail(email, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(email, password, client)
 	user, _ := args.Get(0).(*models.User)
-	token, _ := args.Get(1).(string)
-	return user, token, args.Error(2)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
+
+func (m *AuthServiceMock) RegisterWithPhone(phone, password string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(phone, password, client)
+	user, _ := args.Get(0).(*models.User)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
+
+func (m *AuthServiceMock) UnlockUser(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
 }
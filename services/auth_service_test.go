@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"database/sql"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHardenedLoginErrors(t *testing.T) {
+	hashedPassword, _ := utils.HashPassword("Str0ng-passw0rd")
+	cfg := config.Config{HardenedAuthErrors: true, LockoutThreshold: 5, LockoutBaseSeconds: 60, LockoutMaxSeconds: 3600}
+
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	authService := services.NewAuthService(mockUserRepo, nil, nil, &utils.PasswordPolicy{}, cfg)
+
+	t.Run("Unknown Account", func(t *testing.T) {
+		mockUserRepo.On("FindByEmail", "unknown@name.com").Return(nil, nil).Once()
+
+		_, _, err := authService.LoginWithEmail("unknown@name.com", "Str0ng-passw0rd", services.ClientInfo{})
+
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	})
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(&models.User{ID: 7, Password: hashedPassword}, nil).Once()
+		mockUserRepo.On("RecordLoginFailure", 7).Return(1, nil).Once()
+
+		_, _, err := authService.LoginWithEmail("name@name.com", "wrong-password", services.ClientInfo{})
+
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+	})
+
+	t.Run("Locked Account", func(t *testing.T) {
+		locked := &models.User{
+			ID:          8,
+			Password:    hashedPassword,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+		}
+		mockUserRepo.On("FindByPhone", "+6281234567890").Return(locked, nil).Once()
+
+		_, _, err := authService.LoginWithPhone("+6281234567890", "Str0ng-passw0rd", services.ClientInfo{})
+
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+		mockUserRepo.AssertNotCalled(t, "RecordLoginFailure", 8)
+	})
+}
+
+func TestHardenedRegisterTakenEmail(t *testing.T) {
+	taken := &models.User{ID: 7, Email: utils.NewNullableString("name@name.com")}
+	mockUserRepo := new(repositories.UserRepositoryMock)
+	mockVerificationService := new(services.VerificationServiceMock)
+	authService := services.NewAuthService(mockUserRepo, nil, mockVerificationService, &utils.PasswordPolicy{}, config.Config{HardenedAuthErrors: true})
+
+	noticeSent := make(chan struct{})
+	mockUserRepo.On("FindByEmail", "name@name.com").Return(taken, nil).Once()
+	mockVerificationService.On("SendAccountExistsNotice", taken, services.LoginMethodEmail).
+		Return(nil).
+		Run(func(mock.Arguments) { close(noticeSent) }).
+		Once()
+
+	_, _, err := authService.RegisterWithEmail("name@name.com", "Str0ng-passw0rd", services.ClientInfo{})
+
+	assert.ErrorIs(t, err, services.ErrRegistrationPending)
+	select {
+	case <-noticeSent:
+	case <-time.After(time.Second):
+		t.Fatal("account exists notice was never sent")
+	}
+	mockUserRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+}
+
+func TestLoginLockout(t *testing.T) {
+	hashedPassword, _ := utils.HashPassword("Str0ng-passw0rd")
+	cfg := config.Config{LockoutThreshold: 5, LockoutBaseSeconds: 60, LockoutMaxSeconds: 3600}
+
+	setup := func(user *models.User) (services.AuthService, *repositories.UserRepositoryMock, *services.TokenServiceMock) {
+		mockUserRepo := new(repositories.UserRepositoryMock)
+		mockTokenService := new(services.TokenServiceMock)
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(user, nil)
+		return services.NewAuthService(mockUserRepo, mockTokenService, nil, &utils.PasswordPolicy{}, cfg), mockUserRepo, mockTokenService
+	}
+
+	t.Run("Wrong Password Is Counted", func(t *testing.T) {
+		authService, mockUserRepo, _ := setup(&models.User{ID: 7, Password: hashedPassword})
+		mockUserRepo.On("RecordLoginFailure", 7).Return(1, nil).Once()
+
+		_, _, err := authService.LoginWithEmail("name@name.com", "wrong-password", services.ClientInfo{})
+
+		assert.EqualError(t, err, "invalid password")
+		mockUserRepo.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "LockUntil", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locks At The Threshold", func(t *testing.T) {
+		authService, mockUserRepo, _ := setup(&models.User{ID: 7, Password: hashedPassword, FailedLogins: 4})
+		mockUserRepo.On("RecordLoginFailure", 7).Return(5, nil).Once()
+		mockUserRepo.On("LockUntil", 7, mock.MatchedBy(func(until time.Time) bool {
+			return until.After(time.Now().Add(59*time.Second)) && until.Before(time.Now().Add(61*time.Second))
+		})).Return(nil).Once()
+
+		_, _, err := authService.LoginWithEmail("name@name.com", "wrong-password", services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		assert.Equal(t, time.Minute, lockedErr.RetryAfter)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Lock Doubles Past The Threshold", func(t *testing.T) {
+		authService, mockUserRepo, _ := setup(&models.User{ID: 7, Password: hashedPassword, FailedLogins: 6})
+		mockUserRepo.On("RecordLoginFailure", 7).Return(7, nil).Once()
+		mockUserRepo.On("LockUntil", 7, mock.Anything).Return(nil).Once()
+
+		_, _, err := authService.LoginWithEmail("name@name.com", "wrong-password", services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		assert.Equal(t, 4*time.Minute, lockedErr.RetryAfter)
+	})
+
+	t.Run("Right Password Is Refused While Locked", func(t *testing.T) {
+		authService, mockUserRepo, _ := setup(&models.User{
+			ID:          7,
+			Password:    hashedPassword,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+		})
+
+		_, _, err := authService.LoginWithEmail("name@name.com", "Str0ng-passw0rd", services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		mockUserRepo.AssertNotCalled(t, "ResetLoginFailures", mock.Anything)
+	})
+
+	t.Run("Successful Login Clears The Counter", func(t *testing.T) {
+		user := &models.User{ID: 7, Password: hashedPassword, FailedLogins: 3}
+		authService, mockUserRepo, mockTokenService := setup(user)
+		mockUserRepo.On("ResetLoginFailures", 7).Return(nil).Once()
+		mockTokenService.On("IssueTokens", user, services.LoginMethodEmail, mock.Anything).
+			Return(&services.TokenPair{AccessToken: "token123"}, nil).Once()
+
+		_, tokens, err := authService.LoginWithEmail("name@name.com", "Str0ng-passw0rd", services.ClientInfo{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "token123", tokens.AccessToken)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
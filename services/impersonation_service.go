@@ -0,0 +1,110 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrImpersonateSelf      = errors.New("you cannot impersonate yourself")
+	ErrImpersonateAdmin     = errors.New("users who can manage roles or impersonate cannot be impersonated")
+	ErrAlreadyImpersonating = errors.New("stop impersonating before starting again")
+	ErrNotImpersonating     = errors.New("this token is not impersonating a user")
+	ErrImpersonating        = errors.New("this action is not allowed while impersonating a user")
+)
+
+type Impersonation struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+type ImpersonationService interface {
+	Start(actorClaims *utils.Claims, userID int, client ClientInfo) (*Impersonation, error)
+	Stop(claims *utils.Claims, client ClientInfo) error
+}
+
+type impersonationService struct {
+	userRepo     repositories.UserRepository
+	auditLogRepo repositories.AuditLogRepository
+	tokenService TokenService
+	roleService  RoleService
+}
+
+func NewImpersonationService(userRepo repositories.UserRepository, auditLogRepo repositories.AuditLogRepository, tokenService TokenService, roleService RoleService) ImpersonationService {
+	return &impersonationService{
+		userRepo:     userRepo,
+		auditLogRepo: auditLogRepo,
+		tokenService: tokenService,
+		roleService:  roleService,
+	}
+}
+
+// Start issues a token letting the admin act as the user. Other admins
+// cannot be impersonated, which would let an admin borrow their privileges.
+func (s *impersonationService) Start(actorClaims *utils.Claims, userID int, client ClientInfo) (*Impersonation, error) {
+	if actorClaims.Actor != nil {
+		return nil, ErrAlreadyImpersonating
+	}
+	actorID, err := strconv.Atoi(actorClaims.Subject)
+	if err != nil {
+		return nil, utils.ErrInvalidToken
+	}
+	if actorID == userID {
+		return nil, ErrImpersonateSelf
+	}
+
+	target, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if target == nil {
+		return nil, ErrUserNotFound
+	}
+
+	roles, err := s.roleService.UserRoles(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if s.roleService.HasPermission(roles, PermissionRolesManage) || s.roleService.HasPermission(roles, PermissionImpersonate) {
+		return nil, ErrImpersonateAdmin
+	}
+
+	token, claims, err := s.tokenService.IssueImpersonationToken(actorClaims, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditLogRepo.Create(auditEntry(actorID, AuditImpersonationStarted, target.ID, claims.ID, client)); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return &Impersonation{AccessToken: token, ExpiresAt: claims.ExpiresAt.Time}, nil
+}
+
+// Stop revokes the impersonation token the request was made with.
+func (s *impersonationService) Stop(claims *utils.Claims, client ClientInfo) error {
+	if claims.Actor == nil {
+		return ErrNotImpersonating
+	}
+	actorID, err := strconv.Atoi(claims.Actor.Subject)
+	if err != nil {
+		return utils.ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return utils.ErrInvalidToken
+	}
+
+	if err := s.tokenService.RevokeToken(claims); err != nil {
+		return err
+	}
+
+	if err := s.auditLogRepo.Create(auditEntry(actorID, AuditImpersonationStopped, userID, claims.ID, client)); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
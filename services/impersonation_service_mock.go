@@ -0,0 +1,22 @@
+package services
+
+import (
+	"go-tutuplapak-user/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type ImpersonationServiceMock struct {
+	mock.Mock
+}
+
+func (m *ImpersonationServiceMock) Start(actorClaims *utils.Claims, userID int, client ClientInfo) (*Impersonation, error) {
+	args := m.Called(actorClaims, userID, client)
+	impersonation, _ := args.Get(0).(*Impersonation)
+	return impersonation, args.Error(1)
+}
+
+func (m *ImpersonationServiceMock) Stop(claims *utils.Claims, client ClientInfo) error {
+	args := m.Called(claims, client)
+	return args.Error(0)
+}
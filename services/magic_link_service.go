@@ -0,0 +1,157 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/mailer"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidMagicLink = errors.New("invalid or expired login link")
+
+type MagicLinkService interface {
+	SendLoginLink(email string) (string, error)
+	LoginWithLink(token, nonce string, client ClientInfo) (*models.User, *TokenPair, error)
+}
+
+type magicLinkService struct {
+	userRepo             repositories.UserRepository
+	verificationCodeRepo repositories.VerificationCodeRepository
+	tokenService         TokenService
+	mailer               mailer.Mailer
+	keys                 *utils.KeySet
+	cfg                  config.Config
+}
+
+func NewMagicLinkService(userRepo repositories.UserRepository, verificationCodeRepo repositories.VerificationCodeRepository, tokenService TokenService, mailer mailer.Mailer, keys *utils.KeySet, cfg config.Config) MagicLinkService {
+	return &magicLinkService{
+		userRepo:             userRepo,
+		verificationCodeRepo: verificationCodeRepo,
+		tokenService:         tokenService,
+		mailer:               mailer,
+		keys:                 keys,
+		cfg:                  cfg,
+	}
+}
+
+// SendLoginLink emails a one-time login link if the address belongs to an
+// account and returns the nonce the requesting browser must present along
+// with the link. A nonce is returned and delivery happens in the background
+// even for unknown addresses, so the response does not reveal which exist.
+func (s *magicLinkService) SendLoginLink(email string) (string, error) {
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return nonce, nil
+	}
+
+	go func() {
+		if err := s.sendLink(user, nonce); err != nil {
+			log.Printf("Failed to send login link to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nonce, nil
+}
+
+// LoginWithLink exchanges a login link for tokens. The stored hash covers both
+// the token's jti and the nonce, so a link opened in another browser than the
+// one that asked for it is rejected.
+func (s *magicLinkService) LoginWithLink(token, nonce string, client ClientInfo) (*models.User, *TokenPair, error) {
+	claims, err := utils.ParseJWT(token, s.keys, s.cfg.JWTIssuer, s.cfg.JWTIssuer)
+	if err != nil || claims.TokenUse != utils.TokenUseMagicLink {
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	code, err := s.verificationCodeRepo.Consume(PurposeMagicLink, magicLinkHash(claims.ID, nonce))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if code == nil || strconv.FormatInt(code.UserID.Int64, 10) != claims.Subject {
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	user, err := s.userRepo.FindByID(int(code.UserID.Int64))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil || user.Email.String != code.Target {
+		return nil, nil, ErrInvalidMagicLink
+	}
+
+	// Following the link proves the user can read mail sent to the address.
+	if !user.EmailVerifiedAt.Valid {
+		if _, err := s.userRepo.MarkEmailVerified(user.ID, code.Target); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+		user.EmailVerifiedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		mfaToken, err := s.tokenService.IssueMFAToken(user, LoginMethodEmailLink)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &MFARequiredError{MFAToken: mfaToken}
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodEmailLink, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+func (s *magicLinkService) sendLink(user *models.User, nonce string) error {
+	ttl := time.Minute * time.Duration(s.cfg.MagicLinkExpiryMinutes)
+	claims, err := utils.NewClaims(utils.TokenUseMagicLink, strconv.Itoa(user.ID), LoginMethodEmailLink, s.cfg.JWTIssuer, s.cfg.JWTIssuer, ttl)
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateJWT(claims, s.keys)
+	if err != nil {
+		return err
+	}
+
+	err = s.verificationCodeRepo.Create(&models.VerificationCode{
+		UserID:    sql.NullInt64{Int64: int64(user.ID), Valid: true},
+		Purpose:   PurposeMagicLink,
+		Target:    user.Email.String,
+		CodeHash:  magicLinkHash(claims.ID, nonce),
+		ExpiresAt: claims.ExpiresAt.Time.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := s.cfg.MagicLinkURL + "?token=" + url.QueryEscape(token)
+	return s.mailer.Send(mailer.Message{
+		To:      user.Email.String,
+		Subject: "Your TutupLapak login link",
+		Body: "Hi,\n\n" +
+			"Open the link below in the same browser you asked for it from to log in:\n\n" +
+			link + "\n\n" +
+			fmt.Sprintf("The link works once and expires in %d minutes. If you did not ask to log in, you can ignore this email.\n", s.cfg.MagicLinkExpiryMinutes),
+	})
+}
+
+func magicLinkHash(jti, nonce string) string {
+	return utils.HashToken(jti + "." + nonce)
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MagicLinkServiceMock struct {
+	mock.Mock
+}
+
+func (m *MagicLinkServiceMock) SendLoginLink(email string) (string, error) {
+	args := m.Called(email)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MagicLinkServiceMock) LoginWithLink(token, nonce string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(token, nonce, client)
+	user, _ := args.Get(0).(*models.User)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
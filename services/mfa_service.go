@@ -0,0 +1,253 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidMFACode    = errors.New("invalid verification code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+)
+
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFARequiredError is returned by the login methods instead of tokens when the
+// user has two-factor authentication enabled. MFAToken must be exchanged,
+// together with a code, through MFAService.CompleteLogin.
+type MFARequiredError struct {
+	MFAToken string
+}
+
+func (e *MFARequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+type MFAService interface {
+	EnrollTOTP(user *models.User) (string, string, error)
+	ConfirmTOTP(user *models.User, code string) ([]string, error)
+	DisableTOTP(user *models.User, code string) error
+	CompleteLogin(mfaToken, code string, client ClientInfo) (*models.User, *TokenPair, error)
+}
+
+type mfaService struct {
+	userRepo         repositories.UserRepository
+	recoveryCodeRepo repositories.RecoveryCodeRepository
+	tokenService     TokenService
+	cfg              config.Config
+}
+
+func NewMFAService(userRepo repositories.UserRepository, recoveryCodeRepo repositories.RecoveryCodeRepository, tokenService TokenService, cfg config.Config) MFAService {
+	return &mfaService{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		tokenService:     tokenService,
+		cfg:              cfg,
+	}
+}
+
+// EnrollTOTP generates a pending secret and returns it with its otpauth URI.
+// Calling it again before confirming replaces the pending secret.
+func (s *mfaService) EnrollTOTP(user *models.User) (string, string, error) {
+	if user.TOTPEnabledAt.Valid {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	if err := s.userRepo.SetTOTPSecret(user.ID, secret); err != nil {
+		return "", "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	account := user.Email.String
+	if !user.Email.Valid {
+		account = user.Phone.String
+	}
+
+	return secret, utils.TOTPURI(s.cfg.MFAIssuer, account, secret), nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves their
+// authenticator produces valid codes, and returns a fresh set of recovery
+// codes. The codes are only ever shown here; just their hashes are stored.
+func (s *mfaService) ConfirmTOTP(user *models.User, code string) ([]string, error) {
+	if user.TOTPEnabledAt.Valid {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if !user.TOTPSecret.Valid {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := s.verifyTOTP(user, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+		codes[i] = code
+		hashes[i] = utils.HashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(user.ID, hashes); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if err := s.userRepo.EnableTOTP(user.ID); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return codes, nil
+}
+
+func (s *mfaService) DisableTOTP(user *models.User, code string) error {
+	if !user.TOTPEnabledAt.Valid {
+		return ErrMFANotEnrolled
+	}
+
+	if err := s.checkSecondFactor(user, code); err != nil {
+		return err
+	}
+
+	if err := s.recoveryCodeRepo.DeleteForUser(user.ID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if err := s.userRepo.DisableTOTP(user.ID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
+
+// CompleteLogin exchanges an MFA token and a TOTP or recovery code for real
+// tokens. The MFA token is single use. Wrong codes count towards the same
+// lockout as wrong passwords, and the token dies when the account locks.
+func (s *mfaService) CompleteLogin(mfaToken, code string, client ClientInfo) (*models.User, *TokenPair, error) {
+	user, claims, err := s.tokenService.VerifyMFAToken(mfaToken)
+	if err != nil {
+		if errors.Is(err, utils.ErrInternal) {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	if !user.TOTPEnabledAt.Valid {
+		return nil, nil, ErrMFANotEnrolled
+	}
+
+	if err := s.checkSecondFactor(user, code); err != nil {
+		var lockedErr *AccountLockedError
+		if errors.As(err, &lockedErr) {
+			if err := s.tokenService.RevokeToken(claims); err != nil {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, err
+	}
+
+	if err := s.tokenService.RevokeToken(claims); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, claims.LoginMethod, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// checkSecondFactor verifies a code from a user with two-factor
+// authentication enabled. Wrong codes count towards the same lockout as wrong
+// passwords, and a locked account is refused before the code is looked at.
+func (s *mfaService) checkSecondFactor(user *models.User, code string) error {
+	now := time.Now().UTC()
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(now) {
+		return &AccountLockedError{RetryAfter: user.LockedUntil.Time.Sub(now)}
+	}
+
+	if err := s.verifySecondFactor(user, code); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return err
+		}
+		if lockErr := recordLoginFailure(s.userRepo, s.cfg, user.ID, now); lockErr != nil {
+			return lockErr
+		}
+		return err
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil.Valid {
+		if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+			return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+	}
+	return nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused
+// recovery code.
+func (s *mfaService) verifySecondFactor(user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		return s.verifyTOTP(user, code)
+	}
+
+	used, err := s.recoveryCodeRepo.Consume(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *mfaService) verifyTOTP(user *models.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.TOTPSecret.String, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.userRepo.UseTOTPStep(user.ID, step)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// generateRecoveryCode returns 80 random bits formatted as xxxx-xxxx-xxxx-xxxx.
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))
+	return encoded[0:4] + "-" + encoded[4:8] + "-" + encoded[8:12] + "-" + encoded[12:16], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
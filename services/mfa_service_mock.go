@@ -0,0 +1,34 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MFAServiceMock struct {
+	mock.Mock
+}
+
+func (m *MFAServiceMock) EnrollTOTP(user *models.User) (string, string, error) {
+	args := m.Called(user)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MFAServiceMock) ConfirmTOTP(user *models.User, code string) ([]string, error) {
+	args := m.Called(user, code)
+	codes, _ := args.Get(0).([]string)
+	return codes, args.Error(1)
+}
+
+func (m *MFAServiceMock) DisableTOTP(user *models.User, code string) error {
+	args := m.Called(user, code)
+	return args.Error(0)
+}
+
+func (m *MFAServiceMock) CompleteLogin(mfaToken, code string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(mfaToken, code, client)
+	user, _ := args.Get(0).(*models.User)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
@@ -0,0 +1,129 @@
+package services_test
+
+import (
+	"database/sql"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSecondFactorLockout(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	cfg := config.Config{LockoutThreshold: 5, LockoutBaseSeconds: 60, LockoutMaxSeconds: 3600}
+	claims := &utils.Claims{LoginMethod: "email"}
+	claims.ID = "mfa-jti"
+
+	// wrongCode returns a code outside the window ValidateTOTP accepts.
+	wrongCode := func() string {
+		for i := 0; ; i++ {
+			code := fmt.Sprintf("%06d", i)
+			if _, ok := utils.ValidateTOTP(secret, code, time.Now()); !ok {
+				return code
+			}
+		}
+	}
+
+	setup := func(user *models.User) (services.MFAService, *repositories.UserRepositoryMock, *services.TokenServiceMock) {
+		mockUserRepo := new(repositories.UserRepositoryMock)
+		mockTokenService := new(services.TokenServiceMock)
+		mockTokenService.On("VerifyMFAToken", "mfa123").Return(user, claims, nil)
+		return services.NewMFAService(mockUserRepo, nil, mockTokenService, cfg), mockUserRepo, mockTokenService
+	}
+
+	newUser := func(failedLogins int) *models.User {
+		return &models.User{
+			ID:            7,
+			TOTPSecret:    utils.NewNullableString(secret),
+			TOTPEnabledAt: sql.NullTime{Time: time.Now(), Valid: true},
+			FailedLogins:  failedLogins,
+		}
+	}
+
+	t.Run("Wrong Code Counts As A Failed Login", func(t *testing.T) {
+		mfaService, mockUserRepo, mockTokenService := setup(newUser(0))
+		mockUserRepo.On("RecordLoginFailure", 7).Return(1, nil).Once()
+
+		_, _, err := mfaService.CompleteLogin("mfa123", wrongCode(), services.ClientInfo{})
+
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+		mockUserRepo.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "LockUntil", mock.Anything, mock.Anything)
+		mockTokenService.AssertNotCalled(t, "RevokeToken", mock.Anything)
+	})
+
+	t.Run("Locks The Account At The Threshold", func(t *testing.T) {
+		mfaService, mockUserRepo, mockTokenService := setup(newUser(4))
+		mockUserRepo.On("RecordLoginFailure", 7).Return(5, nil).Once()
+		mockUserRepo.On("LockUntil", 7, mock.Anything).Return(nil).Once()
+		mockTokenService.On("RevokeToken", claims).Return(nil).Once()
+
+		_, _, err := mfaService.CompleteLogin("mfa123", wrongCode(), services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		assert.Equal(t, time.Minute, lockedErr.RetryAfter)
+		mockUserRepo.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+	})
+
+	t.Run("Refuses Codes While Locked", func(t *testing.T) {
+		user := newUser(5)
+		user.LockedUntil = sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}
+		mfaService, mockUserRepo, mockTokenService := setup(user)
+		mockTokenService.On("RevokeToken", claims).Return(nil).Once()
+		code, _ := utils.TOTPCode(secret, time.Now())
+
+		_, _, err := mfaService.CompleteLogin("mfa123", code, services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		mockUserRepo.AssertNotCalled(t, "UseTOTPStep", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Wrong Code To Disable Counts As A Failed Login", func(t *testing.T) {
+		mfaService, mockUserRepo, _ := setup(nil)
+		mockUserRepo.On("RecordLoginFailure", 7).Return(1, nil).Once()
+
+		err := mfaService.DisableTOTP(newUser(0), wrongCode())
+
+		assert.ErrorIs(t, err, services.ErrInvalidMFACode)
+		mockUserRepo.AssertExpectations(t)
+		mockUserRepo.AssertNotCalled(t, "DisableTOTP", mock.Anything)
+	})
+
+	t.Run("Wrong Codes To Disable Lock The Account", func(t *testing.T) {
+		mfaService, mockUserRepo, _ := setup(nil)
+		mockUserRepo.On("RecordLoginFailure", 7).Return(5, nil).Once()
+		mockUserRepo.On("LockUntil", 7, mock.Anything).Return(nil).Once()
+
+		err := mfaService.DisableTOTP(newUser(4), wrongCode())
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		mockUserRepo.AssertNotCalled(t, "DisableTOTP", mock.Anything)
+	})
+
+	t.Run("Right Code Clears The Counter", func(t *testing.T) {
+		mfaService, mockUserRepo, mockTokenService := setup(newUser(3))
+		mockUserRepo.On("UseTOTPStep", 7, mock.Anything).Return(true, nil).Once()
+		mockUserRepo.On("ResetLoginFailures", 7).Return(nil).Once()
+		mockTokenService.On("RevokeToken", claims).Return(nil).Once()
+		mockTokenService.On("IssueTokens", mock.Anything, "email", mock.Anything).
+			Return(&services.TokenPair{AccessToken: "token123"}, nil).Once()
+		code, _ := utils.TOTPCode(secret, time.Now())
+
+		_, tokens, err := mfaService.CompleteLogin("mfa123", code, services.ClientInfo{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "token123", tokens.AccessToken)
+		mockUserRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/oidc"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"log"
+	"time"
+)
+
+var (
+	ErrUnknownOIDCProvider     = errors.New("unknown identity provider")
+	ErrInvalidOIDCState        = errors.New("sign in request expired or was already used, please start again")
+	ErrOIDCLoginFailed         = errors.New("sign in with the identity provider failed")
+	ErrOIDCProviderUnavailable = errors.New("identity provider is unavailable, please try again later")
+	ErrOIDCEmailNotVerified    = errors.New("identity provider did not confirm an email address for this account")
+	ErrOIDCAccountConflict     = errors.New("an account with this email already exists, sign in and verify the email before using this provider")
+)
+
+// OIDCAuthorization is where to send the browser to start a login, with the
+// values needed to finish it.
+type OIDCAuthorization struct {
+	AuthorizationURL string
+	State            string
+	CodeVerifier     string
+}
+
+type OIDCService interface {
+	BeginLogin(provider string) (*OIDCAuthorization, error)
+	FinishLogin(state, code, codeVerifier string, client ClientInfo) (*models.User, *TokenPair, error)
+}
+
+type oidcService struct {
+	userRepo             repositories.UserRepository
+	identityRepo         repositories.UserIdentityRepository
+	verificationCodeRepo repositories.VerificationCodeRepository
+	tokenService         TokenService
+	clients              map[string]*oidc.Client
+	cfg                  config.Config
+}
+
+func NewOIDCService(userRepo repositories.UserRepository, identityRepo repositories.UserIdentityRepository, verificationCodeRepo repositories.VerificationCodeRepository, tokenService TokenService, cfg config.Config) OIDCService {
+	clients := make(map[string]*oidc.Client, len(cfg.OIDCProviders))
+	for name, provider := range cfg.OIDCProviders {
+		clients[name] = oidc.NewClient(oidc.Provider{
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		}, nil)
+	}
+
+	return &oidcService{
+		userRepo:             userRepo,
+		identityRepo:         identityRepo,
+		verificationCodeRepo: verificationCodeRepo,
+		tokenService:         tokenService,
+		clients:              clients,
+		cfg:                  cfg,
+	}
+}
+
+// BeginLogin starts the authorization code flow. The state is stored to
+// find the provider again; the code verifier stays with the client, and the
+// ID token nonce is derived from it so the token only fits this login.
+func (s *oidcService) BeginLogin(provider string) (*OIDCAuthorization, error) {
+	client, ok := s.clients[provider]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	authURL, err := client.AuthCodeURL(state, oidcNonce(verifier), oidc.CodeChallengeS256(verifier))
+	if err != nil {
+		log.Printf("Failed to reach identity provider %s: %v", provider, err)
+		return nil, ErrOIDCProviderUnavailable
+	}
+
+	err = s.verificationCodeRepo.Create(&models.VerificationCode{
+		Purpose:   PurposeOIDCLogin,
+		Target:    provider,
+		CodeHash:  utils.HashToken(state),
+		ExpiresAt: time.Now().UTC().Add(time.Minute * time.Duration(s.cfg.OIDCStateExpiryMinutes)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return &OIDCAuthorization{AuthorizationURL: authURL, State: state, CodeVerifier: verifier}, nil
+}
+
+// FinishLogin redeems the code the provider redirected back with and signs
+// in the account linked to the provider identity, linking or creating one
+// by verified email on first use.
+func (s *oidcService) FinishLogin(state, code, codeVerifier string, client ClientInfo) (*models.User, *TokenPair, error) {
+	stored, err := s.verificationCodeRepo.Consume(PurposeOIDCLogin, utils.HashToken(state))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if stored == nil {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	provider, ok := s.clients[stored.Target]
+	if !ok {
+		return nil, nil, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := provider.Exchange(code, codeVerifier)
+	if err != nil {
+		return nil, nil, providerError(stored.Target, err)
+	}
+	idToken, err := provider.VerifyIDToken(rawIDToken, oidcNonce(codeVerifier))
+	if err != nil {
+		return nil, nil, providerError(stored.Target, err)
+	}
+
+	user, err := s.findOrLinkUser(provider.Issuer(), idToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		mfaToken, err := s.tokenService.IssueMFAToken(user, LoginMethodOIDC)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &MFARequiredError{MFAToken: mfaToken}
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodOIDC, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// findOrLinkUser returns the account linked to the identity. An unknown
+// identity is linked to the account with the same email only when both the
+// provider and this service have verified the address; otherwise whoever
+// registered the address first, without proving they own it, could have the
+// real owner sign in to their account.
+func (s *oidcService) findOrLinkUser(issuer string, idToken *oidc.IDToken) (*models.User, error) {
+	identity, err := s.identityRepo.FindByIssuerSubject(issuer, idToken.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if identity != nil {
+		if err := s.identityRepo.RecordLogin(identity.ID); err != nil {
+			return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+		user, err := s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+		if user == nil {
+			return nil, ErrOIDCLoginFailed
+		}
+		return user, nil
+	}
+
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.FindByEmail(idToken.Email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		if user, err = s.register(idToken.Email); err != nil {
+			return nil, err
+		}
+	} else if !user.EmailVerifiedAt.Valid {
+		return nil, ErrOIDCAccountConflict
+	}
+
+	err = s.identityRepo.Create(&models.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: idToken.Subject,
+		Email:   sql.NullString{String: idToken.Email, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return user, nil
+}
+
+// register creates an account for an email the provider verified. Like
+// phone OTP sign ups, its password is random and can only be set through
+// the reset flow.
+func (s *oidcService) register(email string) (*models.User, error) {
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	hashedPassword, err := utils.HashPassword(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	user := &models.User{
+		Email:    sql.NullString{String: email, Valid: true},
+		Password: hashedPassword,
+	}
+	if err := s.userRepo.CreateUser(user); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	if _, err := s.userRepo.MarkEmailVerified(user.ID, email); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	user.EmailVerifiedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+
+	return user, nil
+}
+
+// providerError tells a login the provider rejected apart from a provider
+// that could not be reached.
+func providerError(provider string, err error) error {
+	if errors.Is(err, oidc.ErrExchangeFailed) || errors.Is(err, oidc.ErrInvalidIDToken) {
+		return fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+	log.Printf("Failed to reach identity provider %s: %v", provider, err)
+	return ErrOIDCProviderUnavailable
+}
+
+// oidcNonce derives the ID token nonce from the PKCE code verifier, which
+// only the client that started the login knows.
+func oidcNonce(codeVerifier string) string {
+	return utils.HashToken("nonce:" + codeVerifier)
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type OIDCServiceMock struct {
+	mock.Mock
+}
+
+func (m *OIDCServiceMock) BeginLogin(provider string) (*OIDCAuthorization, error) {
+	args := m.Called(provider)
+	authorization, _ := args.Get(0).(*OIDCAuthorization)
+	return authorization, args.Error(1)
+}
+
+func (m *OIDCServiceMock) FinishLogin(state, code, codeVerifier string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(state, code, codeVerifier, client)
+	user, _ := args.Get(0).(*models.User)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"time"
+)
+
+const otpDigits = 6
+
+var (
+	ErrInvalidOTP         = errors.New("invalid or expired code")
+	ErrTooManyOTPAttempts = errors.New("too many attempts, request a new code")
+)
+
+// OTPCooldownError is returned when a target asks for codes faster than the
+// resend limits allow.
+type OTPCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OTPCooldownError) Error() string {
+	return "please wait before requesting another code"
+}
+
+// checkResendLimits enforces a cooldown between codes sent to the target and
+// a cap on how many it gets per hour. The limits are read from the stored
+// codes, so they hold across restarts and instances.
+func checkResendLimits(repo repositories.VerificationCodeRepository, purpose, target string, cooldown time.Duration, maxPerHour int) error {
+	now := time.Now().UTC()
+	sent, err := repo.SentSince(purpose, target, now.Add(-time.Hour))
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if len(sent) == 0 {
+		return nil
+	}
+
+	if wait := sent[len(sent)-1].Add(cooldown).Sub(now); wait > 0 {
+		return &OTPCooldownError{RetryAfter: wait}
+	}
+
+	if len(sent) >= maxPerHour {
+		return &OTPCooldownError{RetryAfter: sent[len(sent)-maxPerHour].Add(time.Hour).Sub(now)}
+	}
+
+	return nil
+}
+
+// issueOTP stores a new numeric code for the target and returns it in clear
+// text so the caller can deliver it.
+func issueOTP(repo repositories.VerificationCodeRepository, purpose string, userID sql.NullInt64, target string, ttl time.Duration) (string, error) {
+	code, err := utils.GenerateNumericCode(otpDigits)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	err = repo.Create(&models.VerificationCode{
+		UserID:    userID,
+		Purpose:   purpose,
+		Target:    target,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return code, nil
+}
+
+// checkOTP consumes the latest code sent to the target if it matches. Every
+// guess is counted before it is compared, so concurrent requests cannot get
+// past maxAttempts.
+func checkOTP(repo repositories.VerificationCodeRepository, purpose, target, code string, maxAttempts int) (*models.VerificationCode, error) {
+	stored, err := repo.FindLatestActive(purpose, target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if stored == nil {
+		return nil, ErrInvalidOTP
+	}
+
+	allowed, err := repo.RecordAttempt(stored.ID, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !allowed {
+		return nil, ErrTooManyOTPAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(code)), []byte(stored.CodeHash)) != 1 {
+		return nil, ErrInvalidOTP
+	}
+
+	consumed, err := repo.ConsumeByID(stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !consumed {
+		return nil, ErrInvalidOTP
+	}
+
+	return stored, nil
+}
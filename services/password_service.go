@@ -0,0 +1,238 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/mailer"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/sms"
+	"go-tutuplapak-user/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidIdentifier = errors.New("identifier must be an email address or phone number")
+	ErrWrongPassword     = errors.New("current password is incorrect")
+	ErrSamePassword      = errors.New("new password must differ from the current one")
+)
+
+// PasswordPolicyError lists every rule a new password breaks.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the requirements"
+}
+
+// checkPasswordPolicy returns a PasswordPolicyError if the password breaks the
+// policy. personal holds the owner's email address and phone number.
+func checkPasswordPolicy(policy *utils.PasswordPolicy, password string, personal ...string) error {
+	if violations := policy.Check(password, personal...); len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+type PasswordService interface {
+	ForgotPassword(identifier string) error
+	ResetPassword(identifier, code, newPassword string) error
+	ChangePassword(user *models.User, loginMethod, currentPassword, newPassword string, client ClientInfo) (*TokenPair, error)
+}
+
+type passwordService struct {
+	userRepo             repositories.UserRepository
+	verificationCodeRepo repositories.VerificationCodeRepository
+	sessionService       SessionService
+	tokenService         TokenService
+	mailer               mailer.Mailer
+	smsSender            sms.SMSSender
+	policy               *utils.PasswordPolicy
+	cfg                  config.Config
+}
+
+func NewPasswordService(userRepo repositories.UserRepository, verificationCodeRepo repositories.VerificationCodeRepository, sessionService SessionService, tokenService TokenService, mailer mailer.Mailer, smsSender sms.SMSSender, policy *utils.PasswordPolicy, cfg config.Config) PasswordService {
+	return &passwordService{
+		userRepo:             userRepo,
+		verificationCodeRepo: verificationCodeRepo,
+		sessionService:       sessionService,
+		tokenService:         tokenService,
+		mailer:               mailer,
+		smsSender:            smsSender,
+		policy:               policy,
+		cfg:                  cfg,
+	}
+}
+
+// ForgotPassword sends a reset code to the email address or phone number if
+// it belongs to an account. It succeeds either way, and delivery happens in
+// the background, so neither the result nor the response time tells the
+// caller whether the account exists. Requests past the resend limits are
+// dropped just as quietly.
+func (s *passwordService) ForgotPassword(identifier string) error {
+	user, identifier, err := s.findByIdentifier(identifier)
+	if err != nil {
+		return err
+	}
+
+	// Checked for unknown identifiers too, which never have codes, so both
+	// cases make the same queries.
+	cooldown := time.Second * time.Duration(s.cfg.PasswordResetCooldownSeconds)
+	if err := checkResendLimits(s.verificationCodeRepo, PurposePasswordReset, identifier, cooldown, s.cfg.PasswordResetMaxSendsPerHour); err != nil {
+		var cooldownErr *OTPCooldownError
+		if errors.As(err, &cooldownErr) {
+			return nil
+		}
+		return err
+	}
+
+	if user == nil {
+		return nil
+	}
+
+	go func() {
+		if err := s.sendResetCode(user, identifier); err != nil {
+			log.Printf("Failed to send password reset code to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// ResetPassword sets a new password using a code from ForgotPassword and
+// signs the user out everywhere.
+func (s *passwordService) ResetPassword(identifier, code, newPassword string) error {
+	// Check the policy first so a rejected password does not use up the
+	// code, and before the lookup so the answer is the same whether or not
+	// the identifier has an account.
+	if err := checkPasswordPolicy(s.policy, newPassword, identifier); err != nil {
+		return err
+	}
+
+	user, identifier, err := s.findByIdentifier(identifier)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrInvalidOTP
+	}
+
+	stored, err := checkOTP(s.verificationCodeRepo, PurposePasswordReset, identifier, code, s.cfg.PasswordResetMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if stored.UserID.Int64 != int64(user.ID) {
+		return ErrInvalidOTP
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	// The code proved ownership, so a lockout from guessing the old password
+	// no longer applies.
+	if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return s.sessionService.RevokeAllSessions(user.ID)
+}
+
+// ChangePassword replaces the password of a signed in user. Every existing
+// session ends, including the caller's, so it gets a fresh token pair back.
+func (s *passwordService) ChangePassword(user *models.User, loginMethod, currentPassword, newPassword string, client ClientInfo) (*TokenPair, error) {
+	// Wrong current passwords count towards the login lockout, so a stolen
+	// access token is no shortcut for guessing the password.
+	now := time.Now().UTC()
+	if user.LockedUntil.Valid && user.LockedUntil.Time.After(now) {
+		return nil, &AccountLockedError{RetryAfter: user.LockedUntil.Time.Sub(now)}
+	}
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		if err := recordLoginFailure(s.userRepo, s.cfg, user.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrWrongPassword
+	}
+	if user.FailedLogins > 0 || user.LockedUntil.Valid {
+		if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+			return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+	}
+	if currentPassword == newPassword {
+		return nil, ErrSamePassword
+	}
+	if err := checkPasswordPolicy(s.policy, newPassword, user.Email.String, user.Phone.String); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	if err := s.sessionService.RevokeAllSessions(user.ID); err != nil {
+		return nil, err
+	}
+
+	return s.tokenService.IssueTokens(user, loginMethod, client)
+}
+
+// findByIdentifier looks up the account behind an email address or phone
+// number and returns the identifier in the form codes are stored under.
+func (s *passwordService) findByIdentifier(identifier string) (*models.User, string, error) {
+	kind, normalized, ok := utils.ClassifyIdentifier(identifier)
+	if !ok {
+		return nil, "", ErrInvalidIdentifier
+	}
+
+	var (
+		user *models.User
+		err  error
+	)
+	if kind == utils.IdentifierEmail {
+		user, err = s.userRepo.FindByEmail(normalized)
+	} else {
+		user, err = s.userRepo.FindByPhone(normalized)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return user, normalized, nil
+}
+
+func (s *passwordService) sendResetCode(user *models.User, identifier string) error {
+	ttl := time.Minute * time.Duration(s.cfg.PasswordResetExpiryMinutes)
+	code, err := issueOTP(s.verificationCodeRepo, PurposePasswordReset, sql.NullInt64{Int64: int64(user.ID), Valid: true}, identifier, ttl)
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(identifier, "@") {
+		return s.mailer.Send(mailer.Message{
+			To:      identifier,
+			Subject: "Reset your TutupLapak password",
+			Body: "Hi,\n\n" +
+				"Use this code to reset your password: " + code + "\n\n" +
+				fmt.Sprintf("The code expires in %d minutes. If you did not ask to reset your password, you can ignore this email.\n", s.cfg.PasswordResetExpiryMinutes),
+		})
+	}
+
+	return s.smsSender.Send(sms.Message{
+		To:   identifier,
+		Body: fmt.Sprintf("Your TutupLapak password reset code is %s. It expires in %d minutes.", code, s.cfg.PasswordResetExpiryMinutes),
+	})
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordServiceMock struct {
+	mock.Mock
+}
+
+func (m *PasswordServiceMock) ForgotPassword(identifier string) error {
+	args := m.Called(identifier)
+	return args.Error(0)
+}
+
+func (m *PasswordServiceMock) ResetPassword(identifier, code, newPassword string) error {
+	args := m.Called(identifier, code, newPassword)
+	return args.Error(0)
+}
+
+func (m *PasswordServiceMock) ChangePassword(user *models.User, loginMethod, currentPassword, newPassword string, client ClientInfo) (*TokenPair, error) {
+	args := m.Called(user, loginMethod, currentPassword, newPassword, client)
+	tokens, _ := args.Get(0).(*TokenPair)
+	return tokens, args.Error(1)
+}
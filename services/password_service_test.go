@@ -0,0 +1,183 @@
+package services_test
+
+import (
+	"database/sql"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/mailer"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/sms"
+	"go-tutuplapak-user/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestResetPassword(t *testing.T) {
+	user := &models.User{ID: 7, Email: utils.NewNullableString("name@name.com")}
+	resetCode := &models.VerificationCode{
+		ID:       3,
+		UserID:   sql.NullInt64{Int64: 7, Valid: true},
+		Purpose:  services.PurposePasswordReset,
+		Target:   "name@name.com",
+		CodeHash: utils.HashToken("123456"),
+	}
+
+	setup := func() (services.PasswordService, *repositories.UserRepositoryMock, *repositories.VerificationCodeRepositoryMock, *services.SessionServiceMock) {
+		mockUserRepo := new(repositories.UserRepositoryMock)
+		mockCodeRepo := new(repositories.VerificationCodeRepositoryMock)
+		mockSessionService := new(services.SessionServiceMock)
+		policy := &utils.PasswordPolicy{MinLength: 8, MaxLength: 64, MinCharClasses: 2}
+		cfg := config.Config{PasswordResetMaxAttempts: 5}
+
+		passwordService := services.NewPasswordService(mockUserRepo, mockCodeRepo, mockSessionService, nil, mailer.NewMemoryMailer(), sms.NewMemorySender(), policy, cfg)
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(user, nil)
+		return passwordService, mockUserRepo, mockCodeRepo, mockSessionService
+	}
+
+	t.Run("Weak Password Leaves The Code Unused", func(t *testing.T) {
+		passwordService, _, mockCodeRepo, _ := setup()
+
+		err := passwordService.ResetPassword("name@name.com", "123456", "aaaaaaaa")
+
+		var policyErr *services.PasswordPolicyError
+		assert.ErrorAs(t, err, &policyErr)
+		mockCodeRepo.AssertNotCalled(t, "FindLatestActive", mock.Anything, mock.Anything)
+		mockCodeRepo.AssertNotCalled(t, "ConsumeByID", mock.Anything)
+	})
+
+	t.Run("Weak Password Gets The Same Answer For Unknown Identifiers", func(t *testing.T) {
+		passwordService, mockUserRepo, _, _ := setup()
+		mockUserRepo.On("FindByEmail", "unknown@name.com").Return(nil, nil)
+
+		knownErr := passwordService.ResetPassword("name@name.com", "123456", "aaaaaaaa")
+		unknownErr := passwordService.ResetPassword("unknown@name.com", "123456", "aaaaaaaa")
+
+		assert.Equal(t, knownErr, unknownErr)
+		mockUserRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	})
+
+	t.Run("Password Reset Clears The Lockout", func(t *testing.T) {
+		passwordService, mockUserRepo, mockCodeRepo, mockSessionService := setup()
+		mockCodeRepo.On("FindLatestActive", services.PurposePasswordReset, "name@name.com").Return(resetCode, nil)
+		mockCodeRepo.On("RecordAttempt", 3, 5).Return(true, nil)
+		mockCodeRepo.On("ConsumeByID", 3).Return(true, nil)
+		mockUserRepo.On("UpdatePassword", 7, mock.Anything).Return(nil)
+		mockUserRepo.On("ResetLoginFailures", 7).Return(nil)
+		mockSessionService.On("RevokeAllSessions", 7).Return(nil)
+
+		err := passwordService.ResetPassword("name@name.com", "123456", "Str0ng-passw0rd")
+
+		assert.NoError(t, err)
+		mockUserRepo.AssertCalled(t, "ResetLoginFailures", 7)
+		mockUserRepo.AssertExpectations(t)
+		mockSessionService.AssertExpectations(t)
+	})
+}
+
+func TestForgotPasswordResendLimits(t *testing.T) {
+	user := &models.User{ID: 7, Email: utils.NewNullableString("name@name.com")}
+
+	setup := func(sent []time.Time) (services.PasswordService, *repositories.UserRepositoryMock, *repositories.VerificationCodeRepositoryMock, *mailer.MemoryMailer) {
+		mockUserRepo := new(repositories.UserRepositoryMock)
+		mockCodeRepo := new(repositories.VerificationCodeRepositoryMock)
+		memoryMailer := mailer.NewMemoryMailer()
+		cfg := config.Config{PasswordResetExpiryMinutes: 15, PasswordResetCooldownSeconds: 60, PasswordResetMaxSendsPerHour: 5}
+
+		passwordService := services.NewPasswordService(mockUserRepo, mockCodeRepo, nil, nil, memoryMailer, sms.NewMemorySender(), &utils.PasswordPolicy{}, cfg)
+		mockUserRepo.On("FindByEmail", "name@name.com").Return(user, nil)
+		mockUserRepo.On("FindByEmail", "unknown@name.com").Return(nil, nil)
+		mockCodeRepo.On("SentSince", services.PurposePasswordReset, mock.Anything, mock.Anything).Return(sent, nil)
+		return passwordService, mockUserRepo, mockCodeRepo, memoryMailer
+	}
+
+	t.Run("Code Is Sent", func(t *testing.T) {
+		passwordService, _, mockCodeRepo, memoryMailer := setup(nil)
+		mockCodeRepo.On("Create", mock.Anything).Return(nil).Once()
+
+		err := passwordService.ForgotPassword("name@name.com")
+
+		assert.NoError(t, err)
+		assert.Eventually(t, func() bool { return len(memoryMailer.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Cooldown Drops The Request Quietly", func(t *testing.T) {
+		passwordService, _, mockCodeRepo, _ := setup([]time.Time{time.Now().Add(-10 * time.Second)})
+
+		err := passwordService.ForgotPassword("name@name.com")
+
+		assert.NoError(t, err)
+		mockCodeRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Hourly Cap Drops The Request Quietly", func(t *testing.T) {
+		now := time.Now()
+		sent := []time.Time{now.Add(-50 * time.Minute), now.Add(-40 * time.Minute), now.Add(-30 * time.Minute), now.Add(-20 * time.Minute), now.Add(-10 * time.Minute)}
+		passwordService, _, mockCodeRepo, _ := setup(sent)
+
+		err := passwordService.ForgotPassword("name@name.com")
+
+		assert.NoError(t, err)
+		mockCodeRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("Unknown Identifier Is Checked The Same Way", func(t *testing.T) {
+		passwordService, _, mockCodeRepo, _ := setup(nil)
+
+		err := passwordService.ForgotPassword("unknown@name.com")
+
+		assert.NoError(t, err)
+		mockCodeRepo.AssertCalled(t, "SentSince", services.PurposePasswordReset, "unknown@name.com", mock.Anything)
+		mockCodeRepo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	hashedPassword, _ := utils.HashPassword("Str0ng-passw0rd")
+	cfg := config.Config{LockoutThreshold: 5, LockoutBaseSeconds: 60, LockoutMaxSeconds: 3600}
+
+	setup := func() (services.PasswordService, *repositories.UserRepositoryMock) {
+		mockUserRepo := new(repositories.UserRepositoryMock)
+		return services.NewPasswordService(mockUserRepo, nil, nil, nil, mailer.NewMemoryMailer(), sms.NewMemorySender(), &utils.PasswordPolicy{}, cfg), mockUserRepo
+	}
+
+	t.Run("Wrong Current Password Is Counted", func(t *testing.T) {
+		passwordService, mockUserRepo := setup()
+		mockUserRepo.On("RecordLoginFailure", 7).Return(1, nil).Once()
+
+		_, err := passwordService.ChangePassword(&models.User{ID: 7, Password: hashedPassword}, "email", "wrong-password", "N3w-passw0rd", services.ClientInfo{})
+
+		assert.ErrorIs(t, err, services.ErrWrongPassword)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Locks At The Threshold", func(t *testing.T) {
+		passwordService, mockUserRepo := setup()
+		mockUserRepo.On("RecordLoginFailure", 7).Return(5, nil).Once()
+		mockUserRepo.On("LockUntil", 7, mock.Anything).Return(nil).Once()
+
+		_, err := passwordService.ChangePassword(&models.User{ID: 7, Password: hashedPassword, FailedLogins: 4}, "email", "wrong-password", "N3w-passw0rd", services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("Right Password Is Refused While Locked", func(t *testing.T) {
+		passwordService, mockUserRepo := setup()
+		user := &models.User{
+			ID:          7,
+			Password:    hashedPassword,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+		}
+
+		_, err := passwordService.ChangePassword(user, "email", "Str0ng-passw0rd", "N3w-passw0rd", services.ClientInfo{})
+
+		var lockedErr *services.AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		mockUserRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/sms"
+	"go-tutuplapak-user/utils"
+	"log"
+	"time"
+)
+
+type PhoneLoginService interface {
+	SendLoginCode(phone string) error
+	LoginWithCode(phone, code string, client ClientInfo) (*models.User, *TokenPair, error)
+}
+
+type phoneLoginService struct {
+	userRepo             repositories.UserRepository
+	verificationCodeRepo repositories.VerificationCodeRepository
+	tokenService         TokenService
+	smsSender            sms.SMSSender
+	cfg                  config.Config
+}
+
+func NewPhoneLoginService(userRepo repositories.UserRepository, verificationCodeRepo repositories.VerificationCodeRepository, tokenService TokenService, smsSender sms.SMSSender, cfg config.Config) PhoneLoginService {
+	return &phoneLoginService{
+		userRepo:             userRepo,
+		verificationCodeRepo: verificationCodeRepo,
+		tokenService:         tokenService,
+		smsSender:            smsSender,
+		cfg:                  cfg,
+	}
+}
+
+// SendLoginCode texts a login code to the number. Codes are recorded for
+// unknown numbers too, without being sent unless auto registration is on, so
+// the resend limits behave the same whether or not the number has an account.
+// With HardenedAuthErrors the text goes out in the background, so neither a
+// failed send nor its duration tells the two apart.
+func (s *phoneLoginService) SendLoginCode(phone string) error {
+	if !utils.IsValidPhoneNumber(phone) {
+		return ErrInvalidIdentifier
+	}
+
+	cooldown := time.Second * time.Duration(s.cfg.PhoneOTPResendCooldownSeconds)
+	if err := checkResendLimits(s.verificationCodeRepo, PurposePhoneLogin, phone, cooldown, s.cfg.PhoneOTPMaxSendsPerHour); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByPhone(phone)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	var userID sql.NullInt64
+	if user != nil {
+		userID = sql.NullInt64{Int64: int64(user.ID), Valid: true}
+	}
+
+	ttl := time.Minute * time.Duration(s.cfg.PhoneOTPExpiryMinutes)
+	code, err := issueOTP(s.verificationCodeRepo, PurposePhoneLogin, userID, phone, ttl)
+	if err != nil {
+		return err
+	}
+
+	if user == nil && !s.cfg.PhoneOTPLoginAutoRegister {
+		return nil
+	}
+
+	if s.cfg.HardenedAuthErrors {
+		go func() {
+			if err := s.sendCode(phone, code); err != nil {
+				log.Printf("Failed to send phone login code: %v", err)
+			}
+		}()
+		return nil
+	}
+
+	return s.sendCode(phone, code)
+}
+
+func (s *phoneLoginService) sendCode(phone, code string) error {
+	err := s.smsSender.Send(sms.Message{
+		To:   phone,
+		Body: fmt.Sprintf("Your TutupLapak login code is %s. It expires in %d minutes. Never share it with anyone.", code, s.cfg.PhoneOTPExpiryMinutes),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
+
+// LoginWithCode signs the owner of the number in, creating an account first
+// when the number is new and auto registration is enabled.
+func (s *phoneLoginService) LoginWithCode(phone, code string, client ClientInfo) (*models.User, *TokenPair, error) {
+	if !utils.IsValidPhoneNumber(phone) {
+		return nil, nil, ErrInvalidIdentifier
+	}
+
+	if _, err := checkOTP(s.verificationCodeRepo, PurposePhoneLogin, phone, code, s.cfg.PhoneOTPMaxAttempts); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.FindByPhone(phone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		if !s.cfg.PhoneOTPLoginAutoRegister {
+			return nil, nil, ErrInvalidOTP
+		}
+		if user, err = s.register(phone); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The code reached the number, which proves ownership.
+	if !user.PhoneVerifiedAt.Valid {
+		if _, err := s.userRepo.MarkPhoneVerified(user.ID, phone); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+		user.PhoneVerifiedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+
+	if user.TOTPEnabledAt.Valid {
+		mfaToken, err := s.tokenService.IssueMFAToken(user, LoginMethodPhoneOTP)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &MFARequiredError{MFAToken: mfaToken}
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodPhoneOTP, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// register creates a passwordless account. Its password is random and never
+// shown, so the user can only set one through the reset flow.
+func (s *phoneLoginService) register(phone string) (*models.User, error) {
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	hashedPassword, err := utils.HashPassword(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	user := &models.User{
+		Phone:    sql.NullString{String: phone, Valid: true},
+		Password: hashedPassword,
+	}
+	if err := s.userRepo.CreateUser(user); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return user, nil
+}
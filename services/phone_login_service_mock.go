@@ -0,0 +1,23 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type PhoneLoginServiceMock struct {
+	mock.Mock
+}
+
+func (m *PhoneLoginServiceMock) SendLoginCode(phone string) error {
+	args := m.Called(phone)
+	return args.Error(0)
+}
+
+func (m *PhoneLoginServiceMock) LoginWithCode(phone, code string, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(phone, code, client)
+	user, _ := args.Get(0).(*models.User)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
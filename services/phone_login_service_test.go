@@ -0,0 +1,62 @@
+package services_test
+
+import (
+	"errors"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/services"
+	"go-tutuplapak-user/sms"
+	"go-tutuplapak-user/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// failingSender reports every message it is given and then fails to send it.
+type failingSender struct {
+	attempts chan sms.Message
+}
+
+func (s *failingSender) Send(msg sms.Message) error {
+	s.attempts <- msg
+	return errors.New("gateway unavailable")
+}
+
+func TestSendLoginCode(t *testing.T) {
+	setup := func(cfg config.Config) (services.PhoneLoginService, *failingSender) {
+		mockUserRepo := new(repositories.UserRepositoryMock)
+		mockCodeRepo := new(repositories.VerificationCodeRepositoryMock)
+		mockUserRepo.On("FindByPhone", "+6281234567890").Return(&models.User{ID: 7}, nil)
+		mockCodeRepo.On("SentSince", services.PurposePhoneLogin, "+6281234567890", mock.Anything).Return(nil, nil)
+		mockCodeRepo.On("Create", mock.Anything).Return(nil)
+
+		sender := &failingSender{attempts: make(chan sms.Message, 1)}
+		cfg.PhoneOTPExpiryMinutes = 5
+		return services.NewPhoneLoginService(mockUserRepo, mockCodeRepo, nil, sender, cfg), sender
+	}
+
+	t.Run("Send Failure Is Reported", func(t *testing.T) {
+		phoneLoginService, _ := setup(config.Config{})
+
+		err := phoneLoginService.SendLoginCode("+6281234567890")
+
+		assert.ErrorIs(t, err, utils.ErrInternal)
+	})
+
+	t.Run("Hardened Errors Send In The Background", func(t *testing.T) {
+		phoneLoginService, sender := setup(config.Config{HardenedAuthErrors: true})
+
+		err := phoneLoginService.SendLoginCode("+6281234567890")
+
+		assert.NoError(t, err)
+		select {
+		case msg := <-sender.attempts:
+			assert.Equal(t, "+6281234567890", msg.To)
+		case <-time.After(time.Second):
+			t.Fatal("login code was never sent")
+		}
+	})
+}
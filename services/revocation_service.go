@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"log"
+	"sync"
+	"time"
+)
+
+type RevocationService interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) bool
+	Start(interval time.Duration)
+}
+
+// revocationService keeps every unexpired revocation in memory so token
+// verification never touches the database. Revocations made by this process
+// are visible immediately; those made by other instances are picked up on the
+// next sync.
+type revocationService struct {
+	revokedTokenRepo repositories.RevokedTokenRepository
+
+	mu       sync.RWMutex
+	revoked  map[string]time.Time
+	lastSync time.Time
+}
+
+func NewRevocationService(revokedTokenRepo repositories.RevokedTokenRepository) RevocationService {
+	return &revocationService{
+		revokedTokenRepo: revokedTokenRepo,
+		revoked:          make(map[string]time.Time),
+	}
+}
+
+func (s *revocationService) Revoke(jti string, expiresAt time.Time) error {
+	if !expiresAt.After(time.Now()) {
+		return nil
+	}
+
+	err := s.revokedTokenRepo.Revoke(&models.RevokedToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt.UTC(),
+		RevokedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	s.mu.Lock()
+	s.revoked[jti] = expiresAt
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *revocationService) IsRevoked(jti string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	expiresAt, ok := s.revoked[jti]
+	return ok && expiresAt.After(time.Now())
+}
+
+// Start loads the current revocations and then keeps the cache in sync and
+// prunes expired entries from memory and the database every interval.
+func (s *revocationService) Start(interval time.Duration) {
+	if err := s.sync(); err != nil {
+		log.Printf("Failed to load revoked tokens: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.sync(); err != nil {
+				log.Printf("Failed to sync revoked tokens: %v", err)
+			}
+			s.prune()
+		}
+	}()
+}
+
+func (s *revocationService) sync() error {
+	// Overlap with the previous sync so rows committed late are not missed.
+	since := s.lastSync.Add(-time.Minute)
+	if s.lastSync.IsZero() {
+		since = time.Time{}
+	}
+	startedAt := time.Now().UTC()
+
+	tokens, err := s.revokedTokenRepo.ListRevokedSince(since)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	for _, token := range tokens {
+		s.revoked[token.JTI] = token.ExpiresAt
+	}
+	s.mu.Unlock()
+
+	s.lastSync = startedAt
+	return nil
+}
+
+func (s *revocationService) prune() {
+	now := time.Now()
+
+	s.mu.Lock()
+	for jti, expiresAt := range s.revoked {
+		if !expiresAt.After(now) {
+			delete(s.revoked, jti)
+		}
+	}
+	s.mu.Unlock()
+
+	if _, err := s.revokedTokenRepo.DeleteExpired(now.UTC()); err != nil {
+		log.Printf("Failed to prune revoked tokens: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"slices"
+)
+
+// Roles seeded by the roles migration.
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+	RoleAdmin  = "admin"
+)
+
+// Permissions checked by RequirePermission. They must match the names
+// seeded by the roles migration.
+const (
+	PermissionOrdersCreate   = "orders:create"
+	PermissionProductsManage = "products:manage"
+	PermissionUsersRead      = "users:read"
+	PermissionRolesManage    = "roles:manage"
+	PermissionImpersonate    = "users:impersonate"
+)
+
+// Values of the audit_log action column.
+const (
+	AuditRoleGranted          = "role.grant"
+	AuditRoleRevoked          = "role.revoke"
+	AuditImpersonationStarted = "impersonation.start"
+	AuditImpersonationStopped = "impersonation.stop"
+)
+
+var (
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrRoleAlreadyGranted = errors.New("user already has this role")
+	ErrRoleNotGranted     = errors.New("user does not have this role")
+	ErrDefaultRole        = errors.New("every user has this role, it cannot be granted or revoked")
+	ErrOwnRoles           = errors.New("you cannot change your own roles")
+	ErrPermissionDenied   = errors.New("you do not have permission to do this")
+)
+
+type RoleService interface {
+	UserRoles(userID int) ([]string, error)
+	ListUserRoles(userID int) ([]string, error)
+	HasPermission(roles []string, permission string) bool
+	GrantRole(actor *models.User, userID int, role string, client ClientInfo) ([]string, error)
+	RevokeRole(actor *models.User, userID int, role string, client ClientInfo) ([]string, error)
+}
+
+type roleService struct {
+	roleRepo       repositories.RoleRepository
+	userRepo       repositories.UserRepository
+	sessionService SessionService
+	permissions    map[string][]string
+	cfg            config.Config
+}
+
+// NewRoleService loads the permissions of every role once; changing them
+// takes a migration and a restart.
+func NewRoleService(roleRepo repositories.RoleRepository, userRepo repositories.UserRepository, sessionService SessionService, cfg config.Config) (RoleService, error) {
+	permissions, err := roleRepo.ListRolePermissions()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range cfg.DefaultRoles {
+		if _, ok := permissions[role]; !ok {
+			return nil, fmt.Errorf("%w %q in DEFAULT_ROLES", ErrUnknownRole, role)
+		}
+	}
+
+	return &roleService{
+		roleRepo:       roleRepo,
+		userRepo:       userRepo,
+		sessionService: sessionService,
+		permissions:    permissions,
+		cfg:            cfg,
+	}, nil
+}
+
+// UserRoles returns the default roles followed by those granted to the user.
+func (s *roleService) UserRoles(userID int) ([]string, error) {
+	granted, err := s.roleRepo.ListUserRoles(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	roles := slices.Clone(s.cfg.DefaultRoles)
+	for _, role := range granted {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// ListUserRoles is UserRoles for the admin API, failing for unknown users.
+func (s *roleService) ListUserRoles(userID int) ([]string, error) {
+	if err := s.findUser(userID); err != nil {
+		return nil, err
+	}
+	return s.UserRoles(userID)
+}
+
+func (s *roleService) HasPermission(roles []string, permission string) bool {
+	for _, role := range roles {
+		if slices.Contains(s.permissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantRole gives the user a role. Tokens issued before carry the old roles
+// until they are refreshed.
+func (s *roleService) GrantRole(actor *models.User, userID int, role string, client ClientInfo) ([]string, error) {
+	if err := s.checkChange(actor, userID, role); err != nil {
+		return nil, err
+	}
+
+	granted, err := s.roleRepo.GrantRole(userID, role, auditEntry(actor.ID, AuditRoleGranted, userID, role, client))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !granted {
+		return nil, ErrRoleAlreadyGranted
+	}
+
+	return s.UserRoles(userID)
+}
+
+// RevokeRole takes a role away and ends every session of the user, since
+// their access tokens still carry it.
+func (s *roleService) RevokeRole(actor *models.User, userID int, role string, client ClientInfo) ([]string, error) {
+	if err := s.checkChange(actor, userID, role); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.roleRepo.RevokeRole(userID, role, auditEntry(actor.ID, AuditRoleRevoked, userID, role, client))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !revoked {
+		return nil, ErrRoleNotGranted
+	}
+
+	if err := s.sessionService.RevokeAllSessions(userID); err != nil {
+		return nil, err
+	}
+
+	return s.UserRoles(userID)
+}
+
+// checkChange keeps admins from changing their own roles, so nobody can
+// lock the last admin out or escalate without a second admin.
+func (s *roleService) checkChange(actor *models.User, userID int, role string) error {
+	if actor.ID == userID {
+		return ErrOwnRoles
+	}
+	if _, ok := s.permissions[role]; !ok {
+		return ErrUnknownRole
+	}
+	if slices.Contains(s.cfg.DefaultRoles, role) {
+		return ErrDefaultRole
+	}
+	return s.findUser(userID)
+}
+
+func (s *roleService) findUser(userID int) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func auditEntry(actorID int, action string, userID int, details string, client ClientInfo) *models.AuditEntry {
+	return &models.AuditEntry{
+		ActorID:      sql.NullInt64{Int64: int64(actorID), Valid: true},
+		Action:       action,
+		TargetUserID: sql.NullInt64{Int64: int64(userID), Valid: true},
+		Details:      details,
+		IPAddress:    client.IPAddress,
+	}
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type RoleServiceMock struct {
+	mock.Mock
+}
+
+func (m *RoleServiceMock) UserRoles(userID int) ([]string, error) {
+	args := m.Called(userID)
+	roles, _ := args.Get(0).([]string)
+	return roles, args.Error(1)
+}
+
+func (m *RoleServiceMock) ListUserRoles(userID int) ([]string, error) {
+	args := m.Called(userID)
+	roles, _ := args.Get(0).([]string)
+	return roles, args.Error(1)
+}
+
+func (m *RoleServiceMock) HasPermission(roles []string, permission string) bool {
+	args := m.Called(roles, permission)
+	return args.Bool(0)
+}
+
+func (m *RoleServiceMock) GrantRole(actor *models.User, userID int, role string, client ClientInfo) ([]string, error) {
+	args := m.Called(actor, userID, role, client)
+	roles, _ := args.Get(0).([]string)
+	return roles, args.Error(1)
+}
+
+func (m *RoleServiceMock) RevokeRole(actor *models.User, userID int, role string, client ClientInfo) ([]string, error) {
+	args := m.Called(actor, userID, role, client)
+	roles, _ := args.Get(0).([]string)
+	return roles, args.Error(1)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+// lastSeenResolution bounds how often an active session's last_seen_at is
+// written, so authenticated requests do not each cost an UPDATE.
+const lastSeenResolution = 5 * time.Minute
+
+// ClientInfo describes the device a login request came from.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type SessionService interface {
+	StartSession(userID int, client ClientInfo) (*models.Session, error)
+	ValidateSession(userID, sessionID int) error
+	ListSessions(userID int) ([]models.Session, error)
+	RevokeSession(userID, sessionID int) error
+	RevokeOtherSessions(userID, currentSessionID int) error
+	RevokeAllSessions(userID int) error
+}
+
+type sessionService struct {
+	sessionRepo repositories.SessionRepository
+}
+
+func NewSessionService(sessionRepo repositories.SessionRepository) SessionService {
+	return &sessionService{sessionRepo: sessionRepo}
+}
+
+func (s *sessionService) StartSession(userID int, client ClientInfo) (*models.Session, error) {
+	session := &models.Session{
+		UserID:     userID,
+		DeviceName: utils.DeviceName(client.UserAgent),
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return session, nil
+}
+
+// ValidateSession fails when the session is unknown, owned by someone else or
+// revoked, and otherwise records the activity.
+func (s *sessionService) ValidateSession(userID, sessionID int) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt.Valid {
+		return ErrSessionRevoked
+	}
+
+	now := time.Now().UTC()
+	if now.Sub(session.LastSeenAt) > lastSeenResolution {
+		if err := s.sessionRepo.Touch(session.ID, now); err != nil {
+			return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *sessionService) ListSessions(userID int) ([]models.Session, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return sessions, nil
+}
+
+func (s *sessionService) RevokeSession(userID, sessionID int) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if session == nil || session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	if err := s.sessionRepo.Revoke(session.ID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *sessionService) RevokeOtherSessions(userID, currentSessionID int) error {
+	if err := s.sessionRepo.RevokeAllByUser(userID, currentSessionID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *sessionService) RevokeAllSessions(userID int) error {
+	return s.RevokeOtherSessions(userID, 0)
+}
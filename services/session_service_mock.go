@@ -0,0 +1,43 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type SessionServiceMock struct {
+	mock.Mock
+}
+
+func (m *SessionServiceMock) StartSession(userID int, client ClientInfo) (*models.Session, error) {
+	args := m.Called(userID, client)
+	session, _ := args.Get(0).(*models.Session)
+	return session, args.Error(1)
+}
+
+func (m *SessionServiceMock) ValidateSession(userID, sessionID int) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *SessionServiceMock) ListSessions(userID int) ([]models.Session, error) {
+	args := m.Called(userID)
+	sessions, _ := args.Get(0).([]models.Session)
+	return sessions, args.Error(1)
+}
+
+func (m *SessionServiceMock) RevokeSession(userID, sessionID int) error {
+	args := m.Called(userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *SessionServiceMock) RevokeOtherSessions(userID, currentSessionID int) error {
+	args := m.Called(userID, currentSessionID)
+	return args.Error(0)
+}
+
+func (m *SessionServiceMock) RevokeAllSessions(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
@@ -0,0 +1,369 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values of the login_method claim.
+const (
+	LoginMethodEmail         = "email"
+	LoginMethodPhone         = "phone"
+	LoginMethodEmailLink     = "email_link"
+	LoginMethodPhoneOTP      = "phone_otp"
+	LoginMethodWebAuthn      = "webauthn"
+	LoginMethodOIDC          = "oidc"
+	LoginMethodImpersonation = "impersonation"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrTokenUserNotFound   = errors.New("user not found")
+	ErrWrongTokenUse       = errors.New("token cannot be used for this purpose")
+	ErrTokenBeforePassword = errors.New("token was issued before the password was changed")
+)
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+type TokenService interface {
+	IssueTokens(user *models.User, loginMethod string, client ClientInfo) (*TokenPair, error)
+	Refresh(refreshToken string, client ClientInfo) (*TokenPair, error)
+	VerifyAccessToken(accessToken string) (*models.User, *utils.Claims, error)
+	Logout(user *models.User, claims *utils.Claims, refreshToken string) error
+	IssueMFAToken(user *models.User, loginMethod string) (string, error)
+	IssueImpersonationToken(actorClaims *utils.Claims, target *models.User) (string, *utils.Claims, error)
+	VerifyMFAToken(mfaToken string) (*models.User, *utils.Claims, error)
+	RevokeToken(claims *utils.Claims) error
+	PublicKeys() utils.JWKS
+}
+
+type tokenService struct {
+	userRepo          repositories.UserRepository
+	refreshTokenRepo  repositories.RefreshTokenRepository
+	sessionService    SessionService
+	revocationService RevocationService
+	roleService       RoleService
+	keys              *utils.KeySet
+	cfg               config.Config
+}
+
+func NewTokenService(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepository, sessionService SessionService, revocationService RevocationService, roleService RoleService, keys *utils.KeySet, cfg config.Config) TokenService {
+	return &tokenService{
+		userRepo:          userRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		sessionService:    sessionService,
+		revocationService: revocationService,
+		roleService:       roleService,
+		keys:              keys,
+		cfg:               cfg,
+	}
+}
+
+// IssueTokens starts a new session and refresh token family for the user and
+// returns them together with a fresh access token.
+func (s *tokenService) IssueTokens(user *models.User, loginMethod string, client ClientInfo) (*TokenPair, error) {
+	session, err := s.sessionService.StartSession(user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return s.issue(user, familyID, loginMethod, session.ID)
+}
+
+// Refresh exchanges a refresh token for a new pair. Every refresh token can be
+// used once; presenting one that was already rotated means it leaked, so the
+// whole family is revoked and the legitimate holder has to log in again.
+func (s *tokenService) Refresh(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	stored, err := s.refreshTokenRepo.FindByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if stored == nil || stored.RevokedAt.Valid {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.RotatedAt.Valid {
+		return nil, s.revokeReusedFamily(stored)
+	}
+
+	if time.Now().UTC().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rotated, err := s.refreshTokenRepo.MarkRotated(stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !rotated {
+		return nil, s.revokeReusedFamily(stored)
+	}
+
+	user, err := s.userRepo.FindByID(stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Families created before sessions existed are moved into a new one.
+	sessionID := int(stored.SessionID.Int64)
+	if stored.SessionID.Valid {
+		if err := s.sessionService.ValidateSession(user.ID, sessionID); err != nil {
+			if errors.Is(err, utils.ErrInternal) {
+				return nil, err
+			}
+			return nil, ErrInvalidRefreshToken
+		}
+	} else {
+		session, err := s.sessionService.StartSession(user.ID, client)
+		if err != nil {
+			return nil, err
+		}
+		sessionID = session.ID
+	}
+
+	return s.issue(user, stored.FamilyID, stored.LoginMethod, sessionID)
+}
+
+// VerifyAccessToken checks the token's signature, expiry and revocation status
+// as well as its session, and resolves the user it was issued for.
+// Impersonation tokens live in the session of the admin acting, so they end
+// with it.
+func (s *tokenService) VerifyAccessToken(accessToken string) (*models.User, *utils.Claims, error) {
+	user, claims, err := s.verify(accessToken, utils.TokenUseAccess)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessionID, err := strconv.Atoi(claims.SessionID)
+	if err != nil {
+		return nil, nil, utils.ErrInvalidToken
+	}
+
+	sessionUserID := user.ID
+	if claims.Actor != nil {
+		if sessionUserID, err = strconv.Atoi(claims.Actor.Subject); err != nil {
+			return nil, nil, utils.ErrInvalidToken
+		}
+	}
+
+	if err := s.sessionService.ValidateSession(sessionUserID, sessionID); err != nil {
+		return nil, nil, err
+	}
+
+	return user, claims, nil
+}
+
+// IssueMFAToken returns a short-lived token proving the user passed the
+// password step. It can only be exchanged at the MFA login endpoint.
+func (s *tokenService) IssueMFAToken(user *models.User, loginMethod string) (string, error) {
+	claims, err := utils.NewClaims(
+		utils.TokenUseMFA,
+		strconv.Itoa(user.ID),
+		loginMethod,
+		s.cfg.JWTIssuer,
+		s.cfg.JWTIssuer,
+		time.Minute*time.Duration(s.cfg.MFATokenExpiryMinutes),
+	)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	token, err := utils.GenerateJWT(claims, s.keys)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return token, nil
+}
+
+// IssueImpersonationToken returns a short-lived access token for the target
+// carrying the admin as its act claim. It has no refresh token and is bound
+// to the admin's session.
+func (s *tokenService) IssueImpersonationToken(actorClaims *utils.Claims, target *models.User) (string, *utils.Claims, error) {
+	claims, err := utils.NewClaims(
+		utils.TokenUseAccess,
+		strconv.Itoa(target.ID),
+		LoginMethodImpersonation,
+		s.cfg.JWTIssuer,
+		s.cfg.JWTAudience,
+		time.Minute*time.Duration(s.cfg.ImpersonationExpiryMinutes),
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	claims.SessionID = actorClaims.SessionID
+	claims.Scope = strings.Join(APIKeyScopes, " ")
+	claims.Actor = &utils.ActorClaims{Subject: actorClaims.Subject}
+	claims.Roles, err = s.roleService.UserRoles(target.ID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := utils.GenerateJWT(claims, s.keys)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return token, claims, nil
+}
+
+func (s *tokenService) VerifyMFAToken(mfaToken string) (*models.User, *utils.Claims, error) {
+	return s.verify(mfaToken, utils.TokenUseMFA)
+}
+
+func (s *tokenService) RevokeToken(claims *utils.Claims) error {
+	return s.revocationService.Revoke(claims.ID, claims.ExpiresAt.Time)
+}
+
+// Logout ends the session the access token belongs to, revokes the token
+// itself and, when given, the refresh token family issued alongside it.
+func (s *tokenService) Logout(user *models.User, claims *utils.Claims, refreshToken string) error {
+	if err := s.RevokeToken(claims); err != nil {
+		return err
+	}
+
+	if sessionID, err := strconv.Atoi(claims.SessionID); err == nil {
+		if err := s.sessionService.RevokeSession(user.ID, sessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.refreshTokenRepo.FindByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if stored == nil || stored.UserID != user.ID {
+		return ErrInvalidRefreshToken
+	}
+
+	if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *tokenService) PublicKeys() utils.JWKS {
+	return s.keys.JWKS()
+}
+
+func (s *tokenService) issue(user *models.User, familyID, loginMethod string, sessionID int) (*TokenPair, error) {
+	claims, err := utils.NewClaims(
+		utils.TokenUseAccess,
+		strconv.Itoa(user.ID),
+		loginMethod,
+		s.cfg.JWTIssuer,
+		s.cfg.JWTAudience,
+		time.Minute*time.Duration(s.cfg.JWTExpiryMinutes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	claims.SessionID = strconv.Itoa(sessionID)
+	claims.Scope = strings.Join(APIKeyScopes, " ")
+	claims.Roles, err = s.roleService.UserRoles(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWT(claims, s.keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	err = s.refreshTokenRepo.Create(&models.RefreshToken{
+		UserID:      user.ID,
+		SessionID:   sql.NullInt64{Int64: int64(sessionID), Valid: true},
+		FamilyID:    familyID,
+		LoginMethod: loginMethod,
+		TokenHash:   utils.HashToken(refreshToken),
+		ExpiresAt:   time.Now().UTC().Add(time.Hour * time.Duration(s.cfg.RefreshTokenExpiryHours)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// verify parses a token of the given use, rejects revoked ones and loads the
+// user it was issued for. MFA tokens are audienced to this service only so
+// other services never accept them.
+func (s *tokenService) verify(token, tokenUse string) (*models.User, *utils.Claims, error) {
+	audience := s.cfg.JWTAudience
+	if tokenUse == utils.TokenUseMFA {
+		audience = s.cfg.JWTIssuer
+	}
+
+	claims, err := utils.ParseJWT(token, s.keys, s.cfg.JWTIssuer, audience)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.TokenUse != tokenUse {
+		return nil, nil, ErrWrongTokenUse
+	}
+	if claims.LoginMethod == "" {
+		return nil, nil, utils.ErrInvalidToken
+	}
+
+	if s.revocationService.IsRevoked(claims.ID) {
+		return nil, nil, ErrTokenRevoked
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, nil, utils.ErrInvalidToken
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return nil, nil, ErrTokenUserNotFound
+	}
+
+	// iat only has second precision, so compare against the whole second.
+	if user.PasswordChangedAt.Valid && claims.IssuedAt.Time.Before(user.PasswordChangedAt.Time.Truncate(time.Second)) {
+		return nil, nil, ErrTokenBeforePassword
+	}
+
+	return user, claims, nil
+}
+
+func (s *tokenService) revokeReusedFamily(stored *models.RefreshToken) error {
+	if err := s.refreshTokenRepo.RevokeFamily(stored.FamilyID); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if stored.SessionID.Valid {
+		if err := s.sessionService.RevokeSession(stored.UserID, int(stored.SessionID.Int64)); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+	return ErrRefreshTokenReused
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type TokenServiceMock struct {
+	mock.Mock
+}
+
+func (m *TokenServiceMock) IssueTokens(user *models.User, loginMethod string, client ClientInfo) (*TokenPair, error) {
+	args := m.Called(user, loginMethod, client)
+	tokens, _ := args.Get(0).(*TokenPair)
+	return tokens, args.Error(1)
+}
+
+func (m *TokenServiceMock) Refresh(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	args := m.Called(refreshToken, client)
+	tokens, _ := args.Get(0).(*TokenPair)
+	return tokens, args.Error(1)
+}
+
+func (m *TokenServiceMock) VerifyAccessToken(accessToken string) (*models.User, *utils.Claims, error) {
+	args := m.Called(accessToken)
+	user, _ := args.Get(0).(*models.User)
+	claims, _ := args.Get(1).(*utils.Claims)
+	return user, claims, args.Error(2)
+}
+
+func (m *TokenServiceMock) Logout(user *models.User, claims *utils.Claims, refreshToken string) error {
+	args := m.Called(user, claims, refreshToken)
+	return args.Error(0)
+}
+
+func (m *TokenServiceMock) PublicKeys() utils.JWKS {
+	args := m.Called()
+	jwks, _ := args.Get(0).(utils.JWKS)
+	return jwks
+}
+
+func (m *TokenServiceMock) IssueMFAToken(user *models.User, loginMethod string) (string, error) {
+	args := m.Called(user, loginMethod)
+	return args.String(0), args.Error(1)
+}
+
+func (m *TokenServiceMock) VerifyMFAToken(mfaToken string) (*models.User, *utils.Claims, error) {
+	args := m.Called(mfaToken)
+	user, _ := args.Get(0).(*models.User)
+	claims, _ := args.Get(1).(*utils.Claims)
+	return user, claims, args.Error(2)
+}
+
+func (m *TokenServiceMock) RevokeToken(claims *utils.Claims) error {
+	args := m.Called(claims)
+	return args.Error(0)
+}
+
+func (m *TokenServiceMock) IssueImpersonationToken(actorClaims *utils.Claims, target *models.User) (string, *utils.Claims, error) {
+	args := m.Called(actorClaims, target)
+	claims, _ := args.Get(1).(*utils.Claims)
+	return args.String(0), claims, args.Error(2)
+}
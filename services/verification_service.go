@@ -0,0 +1,231 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/mailer"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/sms"
+	"go-tutuplapak-user/utils"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrEmailAlreadyVerified     = errors.New("email is already verified")
+	ErrNoEmailAddress           = errors.New("account has no email address")
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrPhoneAlreadyVerified     = errors.New("phone is already verified")
+	ErrNoPhoneNumber            = errors.New("account has no phone number")
+)
+
+// Values of verification_codes.purpose.
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposePhoneVerification = "phone_verification"
+	PurposePasswordReset     = "password_reset"
+	PurposeMagicLink         = "magic_link"
+	PurposePhoneLogin        = "phone_login"
+	PurposeWebAuthnRegister  = "webauthn_register"
+	PurposeWebAuthnLogin     = "webauthn_login"
+	PurposeOIDCLogin         = "oidc_login"
+)
+
+type VerificationService interface {
+	SendEmailVerification(user *models.User) error
+	VerifyEmail(token string) error
+	SendPhoneVerification(user *models.User) error
+	VerifyPhone(user *models.User, code string) error
+	SendAccountExistsNotice(user *models.User, channel string) error
+}
+
+type verificationService struct {
+	userRepo             repositories.UserRepository
+	verificationCodeRepo repositories.VerificationCodeRepository
+	mailer               mailer.Mailer
+	smsSender            sms.SMSSender
+	keys                 *utils.KeySet
+	cfg                  config.Config
+}
+
+func NewVerificationService(userRepo repositories.UserRepository, verificationCodeRepo repositories.VerificationCodeRepository, mailer mailer.Mailer, smsSender sms.SMSSender, keys *utils.KeySet, cfg config.Config) VerificationService {
+	return &verificationService{
+		userRepo:             userRepo,
+		verificationCodeRepo: verificationCodeRepo,
+		mailer:               mailer,
+		smsSender:            smsSender,
+		keys:                 keys,
+		cfg:                  cfg,
+	}
+}
+
+// SendEmailVerification mails the user a link carrying a signed token. The
+// token's jti is recorded so the link works only once, and only for the
+// address it was sent to. Sends to an address are limited like codes.
+func (s *verificationService) SendEmailVerification(user *models.User) error {
+	if !user.Email.Valid {
+		return ErrNoEmailAddress
+	}
+	if user.EmailVerifiedAt.Valid {
+		return ErrEmailAlreadyVerified
+	}
+
+	cooldown := time.Second * time.Duration(s.cfg.EmailResendCooldownSeconds)
+	if err := checkResendLimits(s.verificationCodeRepo, PurposeEmailVerification, user.Email.String, cooldown, s.cfg.EmailMaxSendsPerHour); err != nil {
+		return err
+	}
+
+	ttl := time.Hour * time.Duration(s.cfg.EmailVerificationExpiryHours)
+	claims, err := utils.NewClaims(utils.TokenUseEmailVerification, strconv.Itoa(user.ID), "", s.cfg.JWTIssuer, s.cfg.JWTIssuer, ttl)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	token, err := utils.GenerateJWT(claims, s.keys)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	err = s.verificationCodeRepo.Create(&models.VerificationCode{
+		UserID:    sql.NullInt64{Int64: int64(user.ID), Valid: true},
+		Purpose:   PurposeEmailVerification,
+		Target:    user.Email.String,
+		CodeHash:  utils.HashToken(claims.ID),
+		ExpiresAt: claims.ExpiresAt.Time.UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	link := s.cfg.EmailVerificationURL + "?token=" + url.QueryEscape(token)
+	err = s.mailer.Send(mailer.Message{
+		To:      user.Email.String,
+		Subject: "Verify your TutupLapak email address",
+		Body: "Hi,\n\n" +
+			"Please confirm that this is your email address by opening the link below:\n\n" +
+			link + "\n\n" +
+			fmt.Sprintf("The link expires in %d hours. If you did not create a TutupLapak account, you can ignore this email.\n", s.cfg.EmailVerificationExpiryHours),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return nil
+}
+
+func (s *verificationService) VerifyEmail(token string) error {
+	claims, err := utils.ParseJWT(token, s.keys, s.cfg.JWTIssuer, s.cfg.JWTIssuer)
+	if err != nil || claims.TokenUse != utils.TokenUseEmailVerification {
+		return ErrInvalidVerificationToken
+	}
+
+	code, err := s.verificationCodeRepo.Consume(PurposeEmailVerification, utils.HashToken(claims.ID))
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if code == nil || strconv.FormatInt(code.UserID.Int64, 10) != claims.Subject {
+		return ErrInvalidVerificationToken
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(int(code.UserID.Int64), code.Target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !verified {
+		return ErrInvalidVerificationToken
+	}
+
+	return nil
+}
+
+// SendPhoneVerification texts a one-time code to the user's phone. A new code
+// replaces any earlier one that was not used yet. Sends are limited per
+// number like login codes, which also bounds how many codes can be guessed
+// at.
+func (s *verificationService) SendPhoneVerification(user *models.User) error {
+	if !user.Phone.Valid {
+		return ErrNoPhoneNumber
+	}
+	if user.PhoneVerifiedAt.Valid {
+		return ErrPhoneAlreadyVerified
+	}
+
+	cooldown := time.Second * time.Duration(s.cfg.PhoneOTPResendCooldownSeconds)
+	if err := checkResendLimits(s.verificationCodeRepo, PurposePhoneVerification, user.Phone.String, cooldown, s.cfg.PhoneOTPMaxSendsPerHour); err != nil {
+		return err
+	}
+
+	ttl := time.Minute * time.Duration(s.cfg.PhoneOTPExpiryMinutes)
+	code, err := issueOTP(s.verificationCodeRepo, PurposePhoneVerification, sql.NullInt64{Int64: int64(user.ID), Valid: true}, user.Phone.String, ttl)
+	if err != nil {
+		return err
+	}
+
+	err = s.smsSender.Send(sms.Message{
+		To:   user.Phone.String,
+		Body: fmt.Sprintf("Your TutupLapak verification code is %s. It expires in %d minutes.", code, s.cfg.PhoneOTPExpiryMinutes),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return nil
+}
+
+func (s *verificationService) VerifyPhone(user *models.User, code string) error {
+	if !user.Phone.Valid {
+		return ErrNoPhoneNumber
+	}
+	if user.PhoneVerifiedAt.Valid {
+		return ErrPhoneAlreadyVerified
+	}
+
+	stored, err := checkOTP(s.verificationCodeRepo, PurposePhoneVerification, user.Phone.String, code, s.cfg.PhoneOTPMaxAttempts)
+	if err != nil {
+		return err
+	}
+	if stored.UserID.Int64 != int64(user.ID) {
+		return ErrInvalidOTP
+	}
+
+	verified, err := s.userRepo.MarkPhoneVerified(user.ID, stored.Target)
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !verified {
+		return ErrInvalidOTP
+	}
+
+	return nil
+}
+
+// SendAccountExistsNotice tells the owner of an account that someone tried to
+// register again with their email address (channel LoginMethodEmail) or
+// phone number (LoginMethodPhone). It replaces the conflict error when
+// HardenedAuthErrors is on.
+func (s *verificationService) SendAccountExistsNotice(user *models.User, channel string) error {
+	var err error
+	if channel == LoginMethodEmail {
+		err = s.mailer.Send(mailer.Message{
+			To:      user.Email.String,
+			Subject: "You already have a TutupLapak account",
+			Body: "Hi,\n\n" +
+				"Someone tried to create a TutupLapak account with this email address, but it already belongs to your account.\n\n" +
+				"If it was you, sign in or reset your password instead. Otherwise, you can ignore this email.\n",
+		})
+	} else {
+		err = s.smsSender.Send(sms.Message{
+			To:   user.Phone.String,
+			Body: "Someone tried to register this number with TutupLapak, but you already have an account. Sign in or reset your password instead.",
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return nil
+}
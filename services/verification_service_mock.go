@@ -0,0 +1,36 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type VerificationServiceMock struct {
+	mock.Mock
+}
+
+func (m *VerificationServiceMock) SendEmailVerification(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *VerificationServiceMock) VerifyEmail(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *VerificationServiceMock) SendPhoneVerification(user *models.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *VerificationServiceMock) VerifyPhone(user *models.User, code string) error {
+	args := m.Called(user, code)
+	return args.Error(0)
+}
+
+func (m *VerificationServiceMock) SendAccountExistsNotice(user *models.User, channel string) error {
+	args := m.Called(user, channel)
+	return args.Error(0)
+}
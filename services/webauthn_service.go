@@ -0,0 +1,242 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/repositories"
+	"go-tutuplapak-user/utils"
+	"go-tutuplapak-user/webauthn"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrWebAuthnChallenge        = errors.New("passkey request expired or was already used, please start again")
+	ErrInvalidPasskey           = errors.New("passkey could not be verified")
+	ErrPasskeyAlreadyRegistered = errors.New("passkey is already registered")
+)
+
+type WebAuthnService interface {
+	BeginRegistration(user *models.User) (*webauthn.CreationOptions, error)
+	FinishRegistration(user *models.User, credential *webauthn.CredentialCreation) (*models.WebAuthnCredential, error)
+	BeginLogin() (*webauthn.RequestOptions, error)
+	FinishLogin(credential *webauthn.CredentialAssertion, client ClientInfo) (*models.User, *TokenPair, error)
+}
+
+type webAuthnService struct {
+	userRepo             repositories.UserRepository
+	credentialRepo       repositories.WebAuthnCredentialRepository
+	verificationCodeRepo repositories.VerificationCodeRepository
+	tokenService         TokenService
+	rp                   webauthn.Config
+}
+
+func NewWebAuthnService(userRepo repositories.UserRepository, credentialRepo repositories.WebAuthnCredentialRepository, verificationCodeRepo repositories.VerificationCodeRepository, tokenService TokenService, cfg config.Config) WebAuthnService {
+	return &webAuthnService{
+		userRepo:             userRepo,
+		credentialRepo:       credentialRepo,
+		verificationCodeRepo: verificationCodeRepo,
+		tokenService:         tokenService,
+		rp: webauthn.Config{
+			RPID:    cfg.WebAuthnRPID,
+			RPName:  cfg.WebAuthnRPName,
+			Origins: cfg.WebAuthnOrigins,
+			Timeout: time.Second * time.Duration(cfg.WebAuthnTimeoutSeconds),
+		},
+	}
+}
+
+// BeginRegistration starts adding a passkey to the signed in user. The user
+// handle is the decimal user ID, which is how FinishLogin maps a passkey
+// back to its account.
+func (s *webAuthnService) BeginRegistration(user *models.User) (*webauthn.CreationOptions, error) {
+	existing, err := s.credentialRepo.ListByUser(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	exclude := make([]webauthn.CredentialDescriptor, 0, len(existing))
+	for _, credential := range existing {
+		exclude = append(exclude, descriptor(credential))
+	}
+
+	challenge, err := s.newChallenge(PurposeWebAuthnRegister, sql.NullInt64{Int64: int64(user.ID), Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	name := user.Email.String
+	if !user.Email.Valid {
+		name = user.Phone.String
+	}
+
+	options := s.rp.CreationOptions(challenge, webauthn.UserEntity{
+		ID:          []byte(strconv.Itoa(user.ID)),
+		Name:        name,
+		DisplayName: name,
+	}, exclude)
+	return &options, nil
+}
+
+func (s *webAuthnService) FinishRegistration(user *models.User, response *webauthn.CredentialCreation) (*models.WebAuthnCredential, error) {
+	challenge, err := s.consumeChallenge(PurposeWebAuthnRegister, response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID.Int64 != int64(user.ID) {
+		return nil, ErrWebAuthnChallenge
+	}
+
+	verified, err := s.rp.VerifyRegistration(response, challenge.raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	existing, err := s.credentialRepo.FindByCredentialID(verified.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if existing != nil {
+		return nil, ErrPasskeyAlreadyRegistered
+	}
+
+	credential := &models.WebAuthnCredential{
+		UserID:       user.ID,
+		CredentialID: verified.ID,
+		PublicKey:    verified.PublicKey,
+		SignCount:    verified.SignCount,
+		AAGUID:       verified.AAGUID,
+		Transports:   strings.Join(verified.Transports, ","),
+	}
+	if err := s.credentialRepo.Create(credential); err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return credential, nil
+}
+
+// BeginLogin starts a passkey login. No identifier is needed: the
+// authenticator picks the passkey and the response says whose it is.
+func (s *webAuthnService) BeginLogin() (*webauthn.RequestOptions, error) {
+	challenge, err := s.newChallenge(PurposeWebAuthnLogin, sql.NullInt64{})
+	if err != nil {
+		return nil, err
+	}
+
+	options := s.rp.RequestOptions(challenge, nil)
+	return &options, nil
+}
+
+// FinishLogin verifies a passkey assertion and signs its owner in. A passkey
+// is phishing resistant and, since user verification is required, already
+// two factors, so TOTP is not asked for.
+func (s *webAuthnService) FinishLogin(response *webauthn.CredentialAssertion, client ClientInfo) (*models.User, *TokenPair, error) {
+	challenge, err := s.consumeChallenge(PurposeWebAuthnLogin, response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credential, err := s.credentialRepo.FindByCredentialID(response.RawID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if credential == nil {
+		return nil, nil, ErrInvalidPasskey
+	}
+	if len(response.Response.UserHandle) > 0 && string(response.Response.UserHandle) != strconv.Itoa(credential.UserID) {
+		return nil, nil, ErrInvalidPasskey
+	}
+
+	signCount, err := s.rp.VerifyAssertion(response, challenge.raw, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountRegressed) {
+			log.Printf("Possibly cloned passkey %d of user %d: %v", credential.ID, credential.UserID, err)
+		}
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	updated, err := s.credentialRepo.UpdateSignCount(credential.ID, signCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if !updated {
+		return nil, nil, ErrInvalidPasskey
+	}
+
+	user, err := s.userRepo.FindByID(credential.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if user == nil {
+		return nil, nil, ErrInvalidPasskey
+	}
+
+	tokens, err := s.tokenService.IssueTokens(user, LoginMethodWebAuthn, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, tokens, nil
+}
+
+// webAuthnChallenge is a ceremony found by its challenge.
+type webAuthnChallenge struct {
+	*models.VerificationCode
+	raw []byte
+}
+
+// newChallenge stores a single use challenge for one ceremony. Only its hash
+// is kept; the response carries the challenge back in its client data.
+func (s *webAuthnService) newChallenge(purpose string, userID sql.NullInt64) ([]byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	err = s.verificationCodeRepo.Create(&models.VerificationCode{
+		UserID:    userID,
+		Purpose:   purpose,
+		CodeHash:  challengeHash(challenge),
+		ExpiresAt: time.Now().UTC().Add(s.rp.Timeout),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+
+	return challenge, nil
+}
+
+func (s *webAuthnService) consumeChallenge(purpose string, clientDataJSON []byte) (*webAuthnChallenge, error) {
+	raw, err := webauthn.ClientChallenge(clientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasskey, err)
+	}
+
+	code, err := s.verificationCodeRepo.Consume(purpose, challengeHash(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", utils.ErrInternal, err)
+	}
+	if code == nil {
+		return nil, ErrWebAuthnChallenge
+	}
+
+	return &webAuthnChallenge{VerificationCode: code, raw: raw}, nil
+}
+
+func challengeHash(challenge []byte) string {
+	return utils.HashToken(base64.RawURLEncoding.EncodeToString(challenge))
+}
+
+func descriptor(credential models.WebAuthnCredential) webauthn.CredentialDescriptor {
+	d := webauthn.CredentialDescriptor{Type: "public-key", ID: credential.CredentialID}
+	if credential.Transports != "" {
+		d.Transports = strings.Split(credential.Transports, ",")
+	}
+	return d
+}
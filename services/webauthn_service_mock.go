@@ -0,0 +1,37 @@
+package services
+
+import (
+	"go-tutuplapak-user/models"
+	"go-tutuplapak-user/webauthn"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type WebAuthnServiceMock struct {
+	mock.Mock
+}
+
+func (m *WebAuthnServiceMock) BeginRegistration(user *models.User) (*webauthn.CreationOptions, error) {
+	args := m.Called(user)
+	options, _ := args.Get(0).(*webauthn.CreationOptions)
+	return options, args.Error(1)
+}
+
+func (m *WebAuthnServiceMock) FinishRegistration(user *models.User, credential *webauthn.CredentialCreation) (*models.WebAuthnCredential, error) {
+	args := m.Called(user, credential)
+	created, _ := args.Get(0).(*models.WebAuthnCredential)
+	return created, args.Error(1)
+}
+
+func (m *WebAuthnServiceMock) BeginLogin() (*webauthn.RequestOptions, error) {
+	args := m.Called()
+	options, _ := args.Get(0).(*webauthn.RequestOptions)
+	return options, args.Error(1)
+}
+
+func (m *WebAuthnServiceMock) FinishLogin(credential *webauthn.CredentialAssertion, client ClientInfo) (*models.User, *TokenPair, error) {
+	args := m.Called(credential, client)
+	user, _ := args.Get(0).(*models.User)
+	tokens, _ := args.Get(1).(*TokenPair)
+	return user, tokens, args.Error(2)
+}
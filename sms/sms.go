@@ -0,0 +1,29 @@
+package sms
+
+import (
+	"fmt"
+	"go-tutuplapak-user/config"
+)
+
+type Message struct {
+	To   string
+	Body string
+}
+
+// SMSSender delivers short text messages to phone numbers.
+type SMSSender interface {
+	Send(msg Message) error
+}
+
+// New builds the sender selected by SMS_DRIVER: "log" to print messages
+// during development, or "memory" to keep them in process for tests.
+func New(cfg config.Config) (SMSSender, error) {
+	switch cfg.SMSDriver {
+	case "log", "":
+		return NewLogSender(), nil
+	case "memory":
+		return NewMemorySender(), nil
+	default:
+		return nil, fmt.Errorf("unknown sms driver %q", cfg.SMSDriver)
+	}
+}
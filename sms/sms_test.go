@@ -0,0 +1,40 @@
+package sms_test
+
+import (
+	"go-tutuplapak-user/config"
+	"go-tutuplapak-user/sms"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySender(t *testing.T) {
+	s := sms.NewMemorySender()
+
+	require.NoError(t, s.Send(sms.Message{To: "+6281111111111", Body: "first"}))
+	require.NoError(t, s.Send(sms.Message{To: "+6282222222222", Body: "second"}))
+	require.NoError(t, s.Send(sms.Message{To: "+6281111111111", Body: "third"}))
+
+	assert.Len(t, s.Messages(), 3)
+
+	last, ok := s.Last("+6281111111111")
+	assert.True(t, ok)
+	assert.Equal(t, "third", last.Body)
+
+	_, ok = s.Last("+6283333333333")
+	assert.False(t, ok)
+}
+
+func TestNew(t *testing.T) {
+	sender, err := sms.New(config.Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &sms.LogSender{}, sender)
+
+	sender, err = sms.New(config.Config{SMSDriver: "memory"})
+	require.NoError(t, err)
+	assert.IsType(t, &sms.MemorySender{}, sender)
+
+	_, err = sms.New(config.Config{SMSDriver: "pigeon"})
+	assert.Error(t, err)
+}
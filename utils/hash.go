@@ -1,15 +1,199 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var errUnknownHashFormat = errors.New("unknown password hash format")
+
+// PasswordHasher creates password hashes with one algorithm and parameter
+// set. Verify accepts every format this package knows, so stored hashes keep
+// working after the configuration changes; NeedsRehash reports the ones that
+// should be replaced on the user's next login.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, hash string) bool
+	NeedsRehash(hash string) bool
+}
+
+// passwordHasher is used by HashPassword and friends. It stays bcrypt with
+// the default cost until SetPasswordHasher is called at startup.
+var passwordHasher PasswordHasher = BcryptHasher{Cost: bcrypt.DefaultCost}
+
+// dummyHash is a hash of a throwaway password made with passwordHasher, for
+// CheckDummyPasswordHash. It is created on first use.
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+func SetPasswordHasher(hasher PasswordHasher) {
+	passwordHasher = hasher
+	dummyHashOnce = sync.Once{}
+}
+
+// NewPasswordHasher returns the hasher for "argon2id" or "bcrypt".
+func NewPasswordHasher(algorithm string, argon2id Argon2idHasher, bcryptCost int) (PasswordHasher, error) {
+	switch algorithm {
+	case "argon2id":
+		return argon2id, nil
+	case "bcrypt":
+		if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		return BcryptHasher{Cost: bcryptCost}, nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+}
+
 func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(hashed), err
+	return passwordHasher.Hash(password)
 }
 
 func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	return passwordHasher.Verify(password, hash)
+}
+
+func PasswordNeedsRehash(hash string) bool {
+	return passwordHasher.NeedsRehash(hash)
+}
+
+// CheckDummyPasswordHash costs as much as CheckPasswordHash on a real
+// account. Logins for accounts that do not exist call it so the response
+// time does not give them away.
+func CheckDummyPasswordHash(password string) {
+	dummyHashOnce.Do(func() {
+		hash, err := passwordHasher.Hash("dummy password for missing accounts")
+		if err == nil {
+			dummyHash = hash
+		}
+	})
+	passwordHasher.Verify(password, dummyHash)
+}
+
+// Argon2idHasher produces PHC strings such as
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>. Memory is in KiB.
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) Verify(password, hash string) bool {
+	return verifyPasswordHash(password, hash)
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+// BcryptHasher is kept for existing hashes and deployments that need it.
+// bcrypt only looks at the first 72 bytes, so longer passwords are refused
+// instead of being silently truncated.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(hashed), err
+}
+
+func (h BcryptHasher) Verify(password, hash string) bool {
+	return verifyPasswordHash(password, hash)
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	if !isBcrypt(hash) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.Cost
+}
+
+// verifyPasswordHash checks a password against a hash in any supported
+// format, picking the algorithm from the hash itself.
+func verifyPasswordHash(password, hash string) bool {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		params, salt, key, err := parseArgon2id(hash)
+		if err != nil {
+			return false
+		}
+		candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(candidate, key) == 1
+	case isBcrypt(hash):
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	default:
+		return false
+	}
+}
+
+func parseArgon2id(hash string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errUnknownHashFormat
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, errUnknownHashFormat
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errUnknownHashFormat
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
 }
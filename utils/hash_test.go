@@ -0,0 +1,94 @@
+package utils_test
+
+import (
+	"go-tutuplapak-user/utils"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testArgon2id keeps the cost low so the tests stay fast.
+var testArgon2id = utils.Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHasher(t *testing.T) {
+	t.Run("Produces PHC Strings That Verify", func(t *testing.T) {
+		hash, err := testArgon2id.Hash("asdfasdf")
+		require.NoError(t, err)
+
+		assert.Regexp(t, regexp.MustCompile(`^\$argon2id\$v=19\$m=1024,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), hash)
+		assert.True(t, testArgon2id.Verify("asdfasdf", hash))
+		assert.False(t, testArgon2id.Verify("asdfasdg", hash))
+		assert.False(t, testArgon2id.NeedsRehash(hash))
+	})
+
+	t.Run("Salts Every Hash", func(t *testing.T) {
+		first, _ := testArgon2id.Hash("asdfasdf")
+		second, _ := testArgon2id.Hash("asdfasdf")
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Verifies Hashes Made With Other Parameters", func(t *testing.T) {
+		stronger := testArgon2id
+		stronger.Iterations = 2
+		hash, err := stronger.Hash("asdfasdf")
+		require.NoError(t, err)
+
+		assert.True(t, testArgon2id.Verify("asdfasdf", hash))
+		assert.True(t, testArgon2id.NeedsRehash(hash))
+	})
+
+	t.Run("Verifies And Upgrades Bcrypt Hashes", func(t *testing.T) {
+		hash, err := utils.BcryptHasher{Cost: 4}.Hash("asdfasdf")
+		require.NoError(t, err)
+
+		assert.True(t, testArgon2id.Verify("asdfasdf", hash))
+		assert.True(t, testArgon2id.NeedsRehash(hash))
+	})
+
+	t.Run("Rejects Malformed Hashes", func(t *testing.T) {
+		hash, _ := testArgon2id.Hash("asdfasdf")
+
+		for _, broken := range []string{"", "asdfasdf", strings.Replace(hash, "v=19", "v=16", 1), strings.TrimSuffix(hash, hash[strings.LastIndex(hash, "$"):])} {
+			assert.False(t, testArgon2id.Verify("asdfasdf", broken), broken)
+		}
+	})
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher := utils.BcryptHasher{Cost: 5}
+
+	hash, err := hasher.Hash("asdfasdf")
+	require.NoError(t, err)
+
+	assert.True(t, hasher.Verify("asdfasdf", hash))
+	assert.False(t, hasher.Verify("asdfasdg", hash))
+	assert.False(t, hasher.NeedsRehash(hash))
+	assert.True(t, utils.BcryptHasher{Cost: 6}.NeedsRehash(hash))
+
+	argonHash, _ := testArgon2id.Hash("asdfasdf")
+	assert.True(t, hasher.Verify("asdfasdf", argonHash))
+	assert.True(t, hasher.NeedsRehash(argonHash))
+
+	_, err = hasher.Hash(strings.Repeat("a", 73))
+	assert.Error(t, err)
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := utils.NewPasswordHasher("argon2id", testArgon2id, 10)
+	require.NoError(t, err)
+	assert.Equal(t, testArgon2id, hasher)
+
+	hasher, err = utils.NewPasswordHasher("bcrypt", testArgon2id, 12)
+	require.NoError(t, err)
+	assert.Equal(t, utils.BcryptHasher{Cost: 12}, hasher)
+
+	_, err = utils.NewPasswordHasher("bcrypt", testArgon2id, 99)
+	assert.Error(t, err)
+
+	_, err = utils.NewPasswordHasher("md5", testArgon2id, 10)
+	assert.Error(t, err)
+}
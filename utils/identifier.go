@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Kinds of login identifier.
+const (
+	IdentifierEmail = "email"
+	IdentifierPhone = "phone"
+)
+
+var (
+	e164Pattern     = regexp.MustCompile(`^\+[1-9]\d{1,14}$`)
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+)
+
+// ClassifyIdentifier tells whether a user typed an email address or an E.164
+// phone number and returns it in the form accounts are stored under. Spaces,
+// dashes, dots and parentheses are dropped from phone numbers.
+func ClassifyIdentifier(identifier string) (kind, normalized string, ok bool) {
+	identifier = strings.TrimSpace(identifier)
+
+	if strings.Contains(identifier, "@") {
+		address, err := mail.ParseAddress(identifier)
+		if err != nil || address.Address != identifier {
+			return "", "", false
+		}
+		return IdentifierEmail, identifier, true
+	}
+
+	phone := phoneSeparators.Replace(identifier)
+	if e164Pattern.MatchString(phone) {
+		return IdentifierPhone, phone, true
+	}
+
+	return "", "", false
+}
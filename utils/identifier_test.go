@@ -0,0 +1,36 @@
+package utils_test
+
+import (
+	"go-tutuplapak-user/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyIdentifier(t *testing.T) {
+	cases := []struct {
+		input      string
+		kind       string
+		normalized string
+		ok         bool
+	}{
+		{"name@name.com", utils.IdentifierEmail, "name@name.com", true},
+		{"  name@name.com ", utils.IdentifierEmail, "name@name.com", true},
+		{"+6281234567890", utils.IdentifierPhone, "+6281234567890", true},
+		{"+62 812-3456-7890", utils.IdentifierPhone, "+6281234567890", true},
+		{"+1 (415) 555.0100", utils.IdentifierPhone, "+14155550100", true},
+		{"081234567890", "", "", false},
+		{"+0812345678", "", "", false},
+		{"+1234567890123456", "", "", false},
+		{"Name <name@name.com>", "", "", false},
+		{"name@", "", "", false},
+		{"name", "", "", false},
+	}
+
+	for _, c := range cases {
+		kind, normalized, ok := utils.ClassifyIdentifier(c.input)
+		assert.Equal(t, c.ok, ok, c.input)
+		assert.Equal(t, c.kind, kind, c.input)
+		assert.Equal(t, c.normalized, normalized, c.input)
+	}
+}
@@ -1,17 +1,92 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func GenerateJWT(identifier, secret string, expiryHours int) (string, error) {
-	claims := jwt.MapClaims{
-		"identifier": identifier,
-		"exp":        time.Now().Add(time.Hour * time.Duration(expiryHours)).Unix(),
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Values of the token_use claim. Every token type is signed with the same
+// keys, so verifiers must check it before trusting a token for a purpose.
+const (
+	TokenUseAccess            = "access"
+	TokenUseMFA               = "mfa"
+	TokenUseEmailVerification = "email_verification"
+	TokenUseMagicLink         = "magic_link"
+)
+
+type Claims struct {
+	TokenUse    string       `json:"token_use"`
+	LoginMethod string       `json:"login_method"`
+	Scope       string       `json:"scope,omitempty"`
+	SessionID   string       `json:"sid,omitempty"`
+	Roles       []string     `json:"roles,omitempty"`
+	Actor       *ActorClaims `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ActorClaims identify who is acting on behalf of the subject, as in the
+// RFC 8693 act claim. Only impersonation tokens carry them.
+type ActorClaims struct {
+	Subject string `json:"sub"`
+}
+
+// NewClaims builds the standard claim set for a token issued to subject,
+// valid from now for ttl, with a fresh jti.
+func NewClaims(tokenUse, subject, loginMethod, issuer, audience string, ttl time.Duration) (*Claims, error) {
+	jti, err := GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Claims{
+		TokenUse:    tokenUse,
+		LoginMethod: loginMethod,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   subject,
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}, nil
+}
+
+func GenerateJWT(claims *Claims, keys *KeySet) (string, error) {
+	return keys.Sign(claims)
+}
+
+// ParseJWT verifies the signature of a token produced by GenerateJWT and
+// requires every standard claim to be present and valid for the given issuer
+// and audience. Checking token_use and login_method is left to the caller,
+// since which values are acceptable depends on what the token is used for.
+func ParseJWT(tokenString string, keys *KeySet, issuer, audience string) (*Claims, error) {
+	var claims Claims
+	token, err := jwt.ParseWithClaims(tokenString, &claims, keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	switch {
+	case claims.ID == "", claims.Subject == "", claims.TokenUse == "":
+		return nil, ErrInvalidToken
+	case !claims.VerifyExpiresAt(now, true),
+		!claims.VerifyIssuedAt(now, true),
+		!claims.VerifyNotBefore(now, true):
+		return nil, ErrInvalidToken
+	case !claims.VerifyIssuer(issuer, true),
+		!claims.VerifyAudience(audience, true):
+		return nil, ErrInvalidToken
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return &claims, nil
 }
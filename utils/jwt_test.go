@@ -0,0 +1,85 @@
+package utils_test
+
+import (
+	"go-tutuplapak-user/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJWT(t *testing.T) {
+	keys := utils.NewHMACKeySet("secret")
+
+	t.Run("Valid Token Returns Typed Claims", func(t *testing.T) {
+		claims, err := utils.NewClaims(utils.TokenUseAccess, "42", "phone", "issuer", "audience", time.Hour)
+		require.NoError(t, err)
+		token, err := utils.GenerateJWT(claims, keys)
+		require.NoError(t, err)
+
+		parsed, err := utils.ParseJWT(token, keys, "issuer", "audience")
+		require.NoError(t, err)
+		assert.Equal(t, "42", parsed.Subject)
+		assert.Equal(t, "phone", parsed.LoginMethod)
+		assert.Equal(t, "issuer", parsed.Issuer)
+		assert.Equal(t, jwt.ClaimStrings{"audience"}, parsed.Audience)
+		assert.Equal(t, claims.ID, parsed.ID)
+		assert.NotNil(t, parsed.IssuedAt)
+		assert.NotNil(t, parsed.NotBefore)
+	})
+
+	t.Run("Rejects Wrong Issuer And Audience", func(t *testing.T) {
+		claims, err := utils.NewClaims(utils.TokenUseAccess, "42", "email", "issuer", "audience", time.Hour)
+		require.NoError(t, err)
+		token, err := utils.GenerateJWT(claims, keys)
+		require.NoError(t, err)
+
+		_, err = utils.ParseJWT(token, keys, "other-issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+
+		_, err = utils.ParseJWT(token, keys, "issuer", "other-audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("Rejects Expired Token", func(t *testing.T) {
+		claims, err := utils.NewClaims(utils.TokenUseAccess, "42", "email", "issuer", "audience", -time.Minute)
+		require.NoError(t, err)
+		token, err := utils.GenerateJWT(claims, keys)
+		require.NoError(t, err)
+
+		_, err = utils.ParseJWT(token, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("Rejects Token Without Subject Or Token Use", func(t *testing.T) {
+		claims, err := utils.NewClaims(utils.TokenUseAccess, "42", "email", "issuer", "audience", time.Hour)
+		require.NoError(t, err)
+
+		withoutSubject := *claims
+		withoutSubject.Subject = ""
+		token, err := utils.GenerateJWT(&withoutSubject, keys)
+		require.NoError(t, err)
+		_, err = utils.ParseJWT(token, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+
+		withoutUse := *claims
+		withoutUse.TokenUse = ""
+		token, err = utils.GenerateJWT(&withoutUse, keys)
+		require.NoError(t, err)
+		_, err = utils.ParseJWT(token, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("Rejects Token Not Yet Valid", func(t *testing.T) {
+		claims, err := utils.NewClaims(utils.TokenUseAccess, "42", "email", "issuer", "audience", time.Hour)
+		require.NoError(t, err)
+		claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		token, err := utils.GenerateJWT(claims, keys)
+		require.NoError(t, err)
+
+		_, err = utils.ParseJWT(token, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+}
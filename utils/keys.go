@@ -0,0 +1,328 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWK is the public half of a signing key as published in the JWKS document.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+	jwk    JWK
+}
+
+// KeySet holds the key tokens are signed with and every key tokens may be
+// verified with. Asymmetric keys are identified by their RFC 7638 thumbprint,
+// which is stable across restarts and rotations. A non-empty HMAC secret is
+// only used when no signing key file is configured, or, until hmacUntil, to
+// keep verifying HS256 tokens issued before the switch to asymmetric keys.
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.PrivateKey
+
+	verificationKeys map[string]verificationKey
+	hmacSecret       []byte
+	hmacUntil        time.Time
+}
+
+// NewHMACKeySet signs and verifies with a shared HS256 secret.
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{
+		signingMethod:    jwt.SigningMethodHS256,
+		signingKey:       []byte(secret),
+		verificationKeys: map[string]verificationKey{},
+		hmacSecret:       []byte(secret),
+	}
+}
+
+// LoadKeySet reads a PEM encoded RSA or Ed25519 private key to sign with and
+// any number of PEM files (public or private keys) that are still accepted
+// for verification, typically the previous signing keys during a rotation.
+// HS256 tokens signed with hmacSecret stay valid only when acceptHMACUntil is
+// set, and only until then: anyone who still knows the old secret could
+// otherwise mint tokens forever.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string, hmacSecret string, acceptHMACUntil time.Time) (*KeySet, error) {
+	if signingKeyFile == "" {
+		return NewHMACKeySet(hmacSecret), nil
+	}
+
+	privateKey, err := readPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &KeySet{
+		verificationKeys: map[string]verificationKey{},
+	}
+	if !acceptHMACUntil.IsZero() {
+		keys.hmacSecret = []byte(hmacSecret)
+		keys.hmacUntil = acceptHMACUntil
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: key cannot sign", signingKeyFile)
+	}
+	signingKey, err := keys.addVerificationKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", signingKeyFile, err)
+	}
+	keys.signingKID = signingKey.jwk.KeyID
+	keys.signingMethod = signingKey.method
+	keys.signingKey = privateKey
+
+	for _, file := range verificationKeyFiles {
+		publicKey, err := readPublicKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := keys.addVerificationKey(publicKey); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+
+	return keys, nil
+}
+
+// Sign serializes the claims into a JWT carrying the signing key's kid.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	if k.signingKID != "" {
+		token.Header["kid"] = k.signingKID
+	}
+	return token.SignedString(k.signingKey)
+}
+
+func (k *KeySet) acceptsHMAC() bool {
+	return len(k.hmacSecret) > 0 && (k.hmacUntil.IsZero() || time.Now().Before(k.hmacUntil))
+}
+
+// Keyfunc selects the verification key for a parsed token. The key is chosen
+// by kid and must match the algorithm it was registered with, so a public key
+// can never be abused as an HMAC secret.
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && k.acceptsHMAC() {
+			return k.hmacSecret, nil
+		}
+		return nil, errors.New("token has no key id")
+	}
+
+	key, ok := k.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.key, nil
+}
+
+// JWKS returns every public verification key. It is empty when tokens are
+// signed with a shared secret.
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range k.verificationKeys {
+		jwks.Keys = append(jwks.Keys, key.jwk)
+	}
+
+	// Current signing key first, the rest in a stable order.
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		if (jwks.Keys[i].KeyID == k.signingKID) != (jwks.Keys[j].KeyID == k.signingKID) {
+			return jwks.Keys[i].KeyID == k.signingKID
+		}
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+	return jwks
+}
+
+func (k *KeySet) addVerificationKey(publicKey crypto.PublicKey) (verificationKey, error) {
+	var key verificationKey
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		key = verificationKey{
+			method: jwt.SigningMethodRS256,
+			key:    pub,
+			jwk: JWK{
+				KeyType:   "RSA",
+				Use:       "sig",
+				Algorithm: jwt.SigningMethodRS256.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			},
+		}
+	case ed25519.PublicKey:
+		key = verificationKey{
+			method: jwt.SigningMethodEdDSA,
+			key:    pub,
+			jwk: JWK{
+				KeyType:   "OKP",
+				Use:       "sig",
+				Algorithm: jwt.SigningMethodEdDSA.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			},
+		}
+	default:
+		return key, fmt.Errorf("unsupported key type %T, expected RSA or Ed25519", publicKey)
+	}
+
+	key.jwk.KeyID = thumbprint(key.jwk)
+	k.verificationKeys[key.jwk.KeyID] = key
+	return key, nil
+}
+
+// PublicKey decodes a key published by another issuer, returning the only
+// signing method it may be used with. RSA, P-256 and Ed25519 keys are
+// supported.
+func (j JWK) PublicKey() (jwt.SigningMethod, crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, nil, errors.New("invalid RSA exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, nil, fmt.Errorf("RSA key of %d bits is too small", key.N.BitLen())
+		}
+		return jwt.SigningMethodRS256, key, nil
+	case "EC":
+		if j.Curve != "P-256" {
+			return nil, nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(j.X)
+		y, errY := base64.RawURLEncoding.DecodeString(j.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, nil, errors.New("invalid EC coordinates")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if _, err := key.ECDH(); err != nil {
+			return nil, nil, fmt.Errorf("invalid EC point: %w", err)
+		}
+		return jwt.SigningMethodES256, key, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if j.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, nil, errors.New("invalid Ed25519 key")
+		}
+		return jwt.SigningMethodEdDSA, ed25519.PublicKey(x), nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported key type %q", j.KeyType)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, hashing only the required
+// members in lexicographic order.
+func thumbprint(jwk JWK) string {
+	var members interface{}
+	if jwk.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	}
+
+	encoded, _ := json.Marshal(members)
+	sum := sha256.Sum256(encoded)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.PrivateKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", file, block.Type)
+	}
+}
+
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		privateKey, err := readPrivateKey(file)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: key has no public half", file)
+		}
+		return signer.Public(), nil
+	}
+}
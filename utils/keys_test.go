@@ -0,0 +1,182 @@
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"go-tutuplapak-user/utils"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return file
+}
+
+func signToken(t *testing.T, keys *utils.KeySet) string {
+	t.Helper()
+	claims, err := utils.NewClaims(utils.TokenUseAccess, "1", "email", "issuer", "audience", time.Hour)
+	require.NoError(t, err)
+	token, err := utils.GenerateJWT(claims, keys)
+	require.NoError(t, err)
+	return token
+}
+
+func TestKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaFile := writePrivateKey(t, rsaKey)
+	edFile := writePrivateKey(t, edKey)
+
+	t.Run("RS256 Round Trip With Kid", func(t *testing.T) {
+		keys, err := utils.LoadKeySet(rsaFile, nil, "", time.Time{})
+		require.NoError(t, err)
+
+		token := signToken(t, keys)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &utils.Claims{})
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", parsed.Method.Alg())
+		assert.Equal(t, keys.JWKS().Keys[0].KeyID, parsed.Header["kid"])
+
+		claims, err := utils.ParseJWT(token, keys, "issuer", "audience")
+		require.NoError(t, err)
+		assert.Equal(t, "1", claims.Subject)
+	})
+
+	t.Run("Rotation Keeps Old Tokens Valid", func(t *testing.T) {
+		oldKeys, err := utils.LoadKeySet(rsaFile, nil, "", time.Time{})
+		require.NoError(t, err)
+		oldToken := signToken(t, oldKeys)
+
+		rotated, err := utils.LoadKeySet(edFile, []string{rsaFile}, "", time.Time{})
+		require.NoError(t, err)
+
+		jwks := rotated.JWKS()
+		require.Len(t, jwks.Keys, 2)
+		assert.Equal(t, "EdDSA", jwks.Keys[0].Algorithm)
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+		assert.Equal(t, "RS256", jwks.Keys[1].Algorithm)
+
+		_, err = utils.ParseJWT(oldToken, rotated, "issuer", "audience")
+		assert.NoError(t, err)
+
+		newToken := signToken(t, rotated)
+		_, err = utils.ParseJWT(newToken, rotated, "issuer", "audience")
+		assert.NoError(t, err)
+
+		_, err = utils.ParseJWT(newToken, oldKeys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("Public Key Cannot Be Used As HMAC Secret", func(t *testing.T) {
+		keys, err := utils.LoadKeySet(rsaFile, nil, "", time.Time{})
+		require.NoError(t, err)
+		jwk := keys.JWKS().Keys[0]
+
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{ID: "x"})
+		forged.Header["kid"] = jwk.KeyID
+		token, err := forged.SignedString([]byte(jwk.N))
+		require.NoError(t, err)
+
+		_, err = utils.ParseJWT(token, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("Old HMAC Tokens Only Until The Deadline", func(t *testing.T) {
+		legacy := signToken(t, utils.NewHMACKeySet("secret"))
+
+		keys, err := utils.LoadKeySet(rsaFile, nil, "secret", time.Time{})
+		require.NoError(t, err)
+		_, err = utils.ParseJWT(legacy, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+
+		keys, err = utils.LoadKeySet(rsaFile, nil, "secret", time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		_, err = utils.ParseJWT(legacy, keys, "issuer", "audience")
+		assert.NoError(t, err)
+
+		keys, err = utils.LoadKeySet(rsaFile, nil, "secret", time.Now().Add(-time.Second))
+		require.NoError(t, err)
+		_, err = utils.ParseJWT(legacy, keys, "issuer", "audience")
+		assert.ErrorIs(t, err, utils.ErrInvalidToken)
+	})
+
+	t.Run("HMAC Fallback Publishes No Keys", func(t *testing.T) {
+		keys, err := utils.LoadKeySet("", nil, "secret", time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, keys.JWKS().Keys)
+
+		token := signToken(t, keys)
+		claims, err := utils.ParseJWT(token, keys, "issuer", "audience")
+		require.NoError(t, err)
+		assert.Equal(t, "1", claims.Subject)
+	})
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	encode := base64.RawURLEncoding.EncodeToString
+
+	t.Run("Published Keys Decode To The Same Key", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		keys, err := utils.LoadKeySet(writePrivateKey(t, rsaKey), nil, "", time.Time{})
+		require.NoError(t, err)
+
+		method, key, err := keys.JWKS().Keys[0].PublicKey()
+
+		require.NoError(t, err)
+		assert.Equal(t, jwt.SigningMethodRS256, method)
+		assert.True(t, rsaKey.PublicKey.Equal(key))
+	})
+
+	t.Run("P-256", func(t *testing.T) {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		jwk := utils.JWK{KeyType: "EC", Curve: "P-256", X: encode(ecKey.X.FillBytes(make([]byte, 32))), Y: encode(ecKey.Y.FillBytes(make([]byte, 32)))}
+
+		method, key, err := jwk.PublicKey()
+
+		require.NoError(t, err)
+		assert.Equal(t, jwt.SigningMethodES256, method)
+		assert.True(t, ecKey.PublicKey.Equal(key))
+	})
+
+	t.Run("Point Off The Curve", func(t *testing.T) {
+		jwk := utils.JWK{KeyType: "EC", Curve: "P-256", X: encode(make([]byte, 32)), Y: encode(make([]byte, 32))}
+
+		_, _, err := jwk.PublicKey()
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Small RSA Key", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		require.NoError(t, err)
+		jwk := utils.JWK{KeyType: "RSA", N: encode(rsaKey.N.Bytes()), E: "AQAB"}
+
+		_, _, err = jwk.PublicKey()
+
+		assert.ErrorContains(t, err, "too small")
+	})
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy decides which new passwords are acceptable. Zero values
+// switch the corresponding rule off.
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int
+	MinCharClasses int
+	MinEntropyBits float64
+	Breached       *BreachedPasswords
+}
+
+// Check returns a message for every rule the password breaks, or nil.
+// personal holds the user's email address and phone number, neither of which
+// may appear in the password.
+func (p *PasswordPolicy) Check(password string, personal ...string) []string {
+	var violations []string
+
+	length := len([]rune(password))
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters long", p.MaxLength))
+	}
+
+	if p.MinCharClasses > 0 && charClasses(password) < p.MinCharClasses {
+		violations = append(violations, fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharClasses))
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		if fragment, kind := personalFragment(value); fragment != "" && strings.Contains(lowered, fragment) {
+			violations = append(violations, "must not contain your "+kind)
+		}
+	}
+
+	if p.MinEntropyBits > 0 && EstimateEntropy(password) < p.MinEntropyBits {
+		violations = append(violations, "is too easy to guess")
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, "appears in a list of breached passwords")
+	}
+
+	return violations
+}
+
+// EstimateEntropy gives a rough strength in bits: each character is worth
+// log2 of the alphabet the password draws from, except repeats and steps of a
+// sequence such as "aaa" or "123", which are worth one bit.
+func EstimateEntropy(password string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(alphabetSize(password)))
+	bits := perChar
+	for i := 1; i < len(runes); i++ {
+		step := runes[i] - runes[i-1]
+		if step >= -1 && step <= 1 {
+			bits++
+		} else {
+			bits += perChar
+		}
+	}
+	return bits
+}
+
+// BreachedPasswords is a set of SHA-1 hashes of known leaked passwords,
+// grouped by their five character prefix like the k-anonymity range files it
+// is loaded from.
+type BreachedPasswords struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a file of uppercase or lowercase hex SHA-1
+// hashes, one per line, each optionally followed by ":<count>" as in the
+// published dumps. Blank lines and lines starting with # are skipped.
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list := &BreachedPasswords{ranges: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(entry, ":")
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("invalid SHA-1 hash on line %d of breached password list", line)
+		}
+		list.add(strings.ToUpper(hash))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return list, nil
+}
+
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, found := b.ranges[hash[:5]][hash[5:]]
+	return found
+}
+
+func (b *BreachedPasswords) add(hash string) {
+	suffixes, ok := b.ranges[hash[:5]]
+	if !ok {
+		suffixes = make(map[string]struct{})
+		b.ranges[hash[:5]] = suffixes
+	}
+	suffixes[hash[5:]] = struct{}{}
+}
+
+type charSet struct {
+	lower, upper, digit, symbol bool
+}
+
+func charSetOf(password string) charSet {
+	var set charSet
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			set.lower = true
+		case unicode.IsUpper(r):
+			set.upper = true
+		case unicode.IsDigit(r):
+			set.digit = true
+		default:
+			set.symbol = true
+		}
+	}
+	return set
+}
+
+func charClasses(password string) int {
+	var classes int
+	set := charSetOf(password)
+	for _, present := range []bool{set.lower, set.upper, set.digit, set.symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
+
+func alphabetSize(password string) int {
+	var size int
+	set := charSetOf(password)
+	if set.lower {
+		size += 26
+	}
+	if set.upper {
+		size += 26
+	}
+	if set.digit {
+		size += 10
+	}
+	if set.symbol {
+		size += 33
+	}
+	return size
+}
+
+// personalFragment returns the part of an email address or phone number a
+// password must not contain, lowercased, and what to call it. Fragments too
+// short to be meaningful are ignored.
+func personalFragment(value string) (string, string) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if local, _, found := strings.Cut(value, "@"); found {
+		if len(local) < 3 {
+			return "", ""
+		}
+		return local, "email address"
+	}
+
+	digits := strings.TrimPrefix(value, "+")
+	if len(digits) < 6 {
+		return "", ""
+	}
+	// Users often drop the country code, so the last eight digits suffice.
+	if len(digits) > 8 {
+		digits = digits[len(digits)-8:]
+	}
+	return digits, "phone number"
+}
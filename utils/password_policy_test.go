@@ -0,0 +1,78 @@
+package utils_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"go-tutuplapak-user/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := &utils.PasswordPolicy{MinLength: 8, MaxLength: 32, MinCharClasses: 2, MinEntropyBits: 40}
+
+	t.Run("Accepts A Strong Password", func(t *testing.T) {
+		assert.Empty(t, policy.Check("Lapak-Tutup-42", "seller@name.com", "+6281234567890"))
+	})
+
+	t.Run("Lists Every Violated Rule", func(t *testing.T) {
+		violations := policy.Check("aaaaaaa")
+
+		assert.Equal(t, []string{
+			"must be at least 8 characters long",
+			"must mix at least 2 of lowercase letters, uppercase letters, digits and symbols",
+			"is too easy to guess",
+		}, violations)
+	})
+
+	t.Run("Rejects Passwords Containing Email Or Phone", func(t *testing.T) {
+		assert.Contains(t, policy.Check("Seller!2024x", "seller@name.com"), "must not contain your email address")
+		assert.Contains(t, policy.Check("x81234567890!", "+6281234567890"), "must not contain your phone number")
+		assert.Contains(t, policy.Check("Pw-34567890", "+6281234567890"), "must not contain your phone number")
+	})
+
+	t.Run("Rejects Passwords That Are Too Long", func(t *testing.T) {
+		assert.Contains(t, policy.Check(strings.Repeat("Ab3$", 9)), "must be at most 32 characters long")
+	})
+
+	t.Run("Rejects Breached Passwords", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		content := "# sample\n" + sha1Hex("Password123!") + ":52579\n" + strings.ToLower(sha1Hex("Qwerty-2024")) + "\n\n"
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		breached, err := utils.LoadBreachedPasswords(path)
+		require.NoError(t, err)
+
+		withList := *policy
+		withList.Breached = breached
+
+		assert.Contains(t, withList.Check("Password123!"), "appears in a list of breached passwords")
+		assert.Contains(t, withList.Check("Qwerty-2024"), "appears in a list of breached passwords")
+		assert.Empty(t, withList.Check("Lapak-Tutup-42"))
+	})
+
+	t.Run("Refuses A Malformed Breached List", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "breached.txt")
+		require.NoError(t, os.WriteFile(path, []byte("not-a-hash\n"), 0o600))
+
+		_, err := utils.LoadBreachedPasswords(path)
+		assert.Error(t, err)
+	})
+}
+
+func TestEstimateEntropy(t *testing.T) {
+	assert.Zero(t, utils.EstimateEntropy(""))
+	assert.Less(t, utils.EstimateEntropy("aaaaaaaaaaaa"), utils.EstimateEntropy("azqxwsce"))
+	assert.Less(t, utils.EstimateEntropy("12345678"), utils.EstimateEntropy("19283746"))
+	assert.Greater(t, utils.EstimateEntropy("Lapak-Tutup-42"), 60.0)
+}
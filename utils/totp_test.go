@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"encoding/base32"
+	"go-tutuplapak-user/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238 appendix B, SHA1 seed, truncated to six digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	t.Run("RFC 6238 Test Vectors", func(t *testing.T) {
+		vectors := map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1111111111: "050471",
+			1234567890: "005924",
+			2000000000: "279037",
+		}
+		for unix, expected := range vectors {
+			code, err := utils.TOTPCode(secret, time.Unix(unix, 0))
+			require.NoError(t, err)
+			assert.Equal(t, expected, code, "time %d", unix)
+		}
+	})
+
+	t.Run("Accepts Adjacent Steps Only", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		previous, _ := utils.TOTPCode(secret, now.Add(-30*time.Second))
+		stale, _ := utils.TOTPCode(secret, now.Add(-90*time.Second))
+
+		step, ok := utils.ValidateTOTP(secret, previous, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30-1, step)
+
+		_, ok = utils.ValidateTOTP(secret, stale, now)
+		assert.False(t, ok)
+
+		_, ok = utils.ValidateTOTP(secret, "12345", now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Generated Secret Round Trips", func(t *testing.T) {
+		generated, err := utils.GenerateTOTPSecret()
+		require.NoError(t, err)
+
+		now := time.Now()
+		code, err := utils.TOTPCode(generated, now)
+		require.NoError(t, err)
+		_, ok := utils.ValidateTOTP(generated, code, now)
+		assert.True(t, ok)
+
+		uri := utils.TOTPURI("TutupLapak", "name@name.com", generated)
+		assert.Contains(t, uri, "otpauth://totp/TutupLapak:name@name.com?")
+		assert.Contains(t, uri, "secret="+generated)
+	})
+}
@@ -0,0 +1,60 @@
+package utils
+
+import "strings"
+
+// DeviceName turns a User-Agent header into a short label such as
+// "Chrome on Android" for the session list. It only knows the common
+// browsers and platforms; anything else falls back to the raw product token.
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := firstMatch(userAgent, []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"Safari/", "Safari"},
+		{"okhttp/", "Android app"},
+		{"Dart/", "Mobile app"},
+		{"CFNetwork/", "iOS app"},
+		{"curl/", "curl"},
+	})
+	platform := firstMatch(userAgent, []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	})
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+
+	product, _, _ := strings.Cut(userAgent, " ")
+	if len(product) > 64 {
+		product = product[:64]
+	}
+	return product
+}
+
+func firstMatch(userAgent string, candidates []struct{ token, name string }) string {
+	for _, candidate := range candidates {
+		if strings.Contains(userAgent, candidate.token) {
+			return candidate.name
+		}
+	}
+	return ""
+}
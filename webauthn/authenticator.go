@@ -0,0 +1,159 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// SoftwareAuthenticator is a passkey kept in memory, standing in for a
+// browser and authenticator in tests and local development. It holds one
+// ES256 credential and answers with "none" attestation.
+type SoftwareAuthenticator struct {
+	Origin string
+
+	// SignCount is the counter sent with the next assertion, minus one.
+	// Tests may set it back to simulate a cloned authenticator.
+	SignCount uint32
+
+	// SkipUserVerification answers without the user verified flag, like an
+	// authenticator used without its PIN or biometric.
+	SkipUserVerification bool
+
+	key          *ecdsa.PrivateKey
+	rpID         string
+	credentialID []byte
+	userHandle   []byte
+}
+
+func NewSoftwareAuthenticator(origin string) (*SoftwareAuthenticator, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SoftwareAuthenticator{Origin: origin, key: key}, nil
+}
+
+// CredentialID returns the ID of the credential made by Create, or nil.
+func (a *SoftwareAuthenticator) CredentialID() []byte {
+	return a.credentialID
+}
+
+// Create makes a new credential, replacing any earlier one, the way
+// navigator.credentials.create() would.
+func (a *SoftwareAuthenticator) Create(options CreationOptions) (*CredentialCreation, error) {
+	if !slices.ContainsFunc(options.PubKeyCredParams, func(p CredentialParameter) bool { return p.Alg == AlgES256 }) {
+		return nil, errors.New("webauthn: ES256 is not an accepted algorithm")
+	}
+
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		return nil, err
+	}
+	for _, excluded := range options.ExcludeCredentials {
+		if slices.Equal(excluded.ID, a.credentialID) {
+			return nil, errors.New("webauthn: authenticator is already registered")
+		}
+	}
+
+	coseKey, err := encodeES256Key(&a.key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	a.rpID = options.RP.ID
+	a.credentialID = credentialID
+	a.userHandle = options.User.ID
+
+	authData := a.authenticatorData(flagUserPresent | flagUserVerified | flagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...) // AAGUID, all zero for "none"
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(credentialID)))
+	authData = append(authData, credentialID...)
+	authData = append(authData, coseKey...)
+
+	attestationObject := encodeCBOR(cborOrderedMap{
+		{"fmt", "none"},
+		{"attStmt", cborOrderedMap{}},
+		{"authData", authData},
+	})
+
+	clientDataJSON, err := a.clientData(ceremonyCreate, options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialCreation{
+		ID:    base64.RawURLEncoding.EncodeToString(credentialID),
+		RawID: credentialID,
+		Type:  credentialTypePublicKey,
+		Response: AttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+			Transports:        []string{"internal"},
+		},
+	}, nil
+}
+
+// Get signs the challenge with the credential, the way
+// navigator.credentials.get() would.
+func (a *SoftwareAuthenticator) Get(options RequestOptions) (*CredentialAssertion, error) {
+	if a.credentialID == nil || options.RPID != a.rpID {
+		return nil, errors.New("webauthn: no credential for this relying party")
+	}
+	if len(options.AllowCredentials) > 0 && !slices.ContainsFunc(options.AllowCredentials, func(d CredentialDescriptor) bool {
+		return slices.Equal(d.ID, a.credentialID)
+	}) {
+		return nil, errors.New("webauthn: credential is not allowed")
+	}
+
+	a.SignCount++
+	flags := byte(flagUserPresent | flagUserVerified)
+	if a.SkipUserVerification {
+		flags = flagUserPresent
+	}
+	authData := a.authenticatorData(flags)
+
+	clientDataJSON, err := a.clientData(ceremonyGet, options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialAssertion{
+		ID:    base64.RawURLEncoding.EncodeToString(a.credentialID),
+		RawID: a.credentialID,
+		Type:  credentialTypePublicKey,
+		Response: AssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        a.userHandle,
+		},
+	}, nil
+}
+
+func (a *SoftwareAuthenticator) authenticatorData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.SignCount)
+}
+
+func (a *SoftwareAuthenticator) clientData(ceremony string, challenge []byte) ([]byte, error) {
+	return json.Marshal(clientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    a.Origin,
+	})
+}
@@ -0,0 +1,210 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// This file implements the part of CBOR (RFC 8949) that WebAuthn needs:
+// integers, byte and text strings, arrays, maps, booleans and null, all with
+// definite lengths. Decoded integers are int64, maps are
+// map[interface{}]interface{} keyed by int64 or string.
+
+var errInvalidCBOR = errors.New("invalid CBOR")
+
+const maxCBORDepth = 16
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborSimple   = 7
+)
+
+// decodeCBOR reads one item from the start of data and returns it together
+// with the bytes that follow it.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if len(data) == 0 || depth > maxCBORDepth {
+		return nil, nil, errInvalidCBOR
+	}
+
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22:
+			return nil, data, nil
+		}
+		return nil, nil, errInvalidCBOR
+	}
+
+	arg, data, err := readCBORArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, nil, errInvalidCBOR
+		}
+		return int64(arg), data, nil
+
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, nil, errInvalidCBOR
+		}
+		return -1 - int64(arg), data, nil
+
+	case cborBytes, cborText:
+		if arg > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		value := data[:arg]
+		if major == cborText {
+			return string(value), data[arg:], nil
+		}
+		return append([]byte(nil), value...), data[arg:], nil
+
+	case cborArray:
+		if arg > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+
+	case cborMap:
+		if arg > uint64(len(data)) {
+			return nil, nil, errInvalidCBOR
+		}
+		items := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errInvalidCBOR
+			}
+			if _, exists := items[key]; exists {
+				return nil, nil, errInvalidCBOR
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items[key] = value
+		}
+		return items, data, nil
+	}
+
+	// Tags and floats do not occur in WebAuthn structures.
+	return nil, nil, errInvalidCBOR
+}
+
+func readCBORArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, errInvalidCBOR
+}
+
+// cborPair is one entry of a cborOrderedMap.
+type cborPair struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// cborOrderedMap is encoded with its entries in the order given, which lets
+// the caller produce the canonical order CTAP2 expects.
+type cborOrderedMap []cborPair
+
+// encodeCBOR encodes int, int64, []byte, string, bool, nil, []interface{} and
+// cborOrderedMap values.
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		return encodeCBORInt(int64(v))
+	case int64:
+		return encodeCBORInt(v)
+	case []byte:
+		return append(encodeCBORHead(cborBytes, uint64(len(v))), v...)
+	case string:
+		return append(encodeCBORHead(cborText, uint64(len(v))), v...)
+	case bool:
+		if v {
+			return []byte{cborSimple<<5 | 21}
+		}
+		return []byte{cborSimple<<5 | 20}
+	case nil:
+		return []byte{cborSimple<<5 | 22}
+	case []interface{}:
+		out := encodeCBORHead(cborArray, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborOrderedMap:
+		out := encodeCBORHead(cborMap, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, encodeCBOR(pair.Key)...)
+			out = append(out, encodeCBOR(pair.Value)...)
+		}
+		return out
+	}
+	panic("webauthn: cannot encode CBOR value")
+}
+
+func encodeCBORInt(v int64) []byte {
+	if v < 0 {
+		return encodeCBORHead(cborNegative, uint64(-1-v))
+	}
+	return encodeCBORHead(cborUnsigned, uint64(v))
+}
+
+func encodeCBORHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= math.MaxUint8:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+}
@@ -0,0 +1,139 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers (RFC 9053) accepted for credentials, in order of
+// preference.
+const (
+	AlgEdDSA = -8
+	AlgES256 = -7
+	AlgRS256 = -257
+)
+
+// COSE key parameters and values.
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+
+	coseCurve = -1 // EC2 and OKP
+	coseX     = -2 // EC2 and OKP
+	coseY     = -3 // EC2
+	coseN     = -1 // RSA
+	coseE     = -2 // RSA
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+const minRSAKeyBits = 2048
+
+var errUnsupportedKey = errors.New("unsupported public key")
+
+// publicKey is a parsed COSE_Key that can check signatures made by the
+// authenticator.
+type publicKey struct {
+	algorithm int64
+	key       crypto.PublicKey
+}
+
+func parsePublicKey(coseKey []byte) (*publicKey, error) {
+	item, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, err
+	}
+	params, ok := item.(map[interface{}]interface{})
+	if !ok || len(rest) != 0 {
+		return nil, errInvalidCBOR
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	algorithm, _ := params[int64(coseAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == AlgES256:
+		curve, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		y, _ := params[int64(coseY)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errUnsupportedKey
+		}
+		// ecdh rejects points that are not on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{algorithm: algorithm, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+
+	case keyType == coseKeyTypeOKP && algorithm == AlgEdDSA:
+		curve, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{algorithm: algorithm, key: ed25519.PublicKey(x)}, nil
+
+	case keyType == coseKeyTypeRSA && algorithm == AlgRS256:
+		n, _ := params[int64(coseN)].([]byte)
+		e, _ := params[int64(coseE)].([]byte)
+		modulus := new(big.Int).SetBytes(n)
+		exponent := new(big.Int).SetBytes(e)
+		if modulus.BitLen() < minRSAKeyBits || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errUnsupportedKey
+		}
+		return &publicKey{algorithm: algorithm, key: &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}}, nil
+	}
+
+	return nil, fmt.Errorf("%w: key type %d with algorithm %d", errUnsupportedKey, keyType, algorithm)
+}
+
+func (k *publicKey) verify(data, signature []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
+
+// encodeES256Key returns the COSE_Key of a P-256 public key in CTAP2
+// canonical order.
+func encodeES256Key(key *ecdsa.PublicKey) ([]byte, error) {
+	ecdhKey, err := key.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	point := ecdhKey.Bytes()
+	x, y := point[1:33], point[33:]
+
+	return encodeCBOR(cborOrderedMap{
+		{int64(coseKeyType), int64(coseKeyTypeEC2)},
+		{int64(coseAlgorithm), int64(AlgES256)},
+		{int64(coseCurve), int64(coseCurveP256)},
+		{int64(coseX), x},
+		{int64(coseY), y},
+	}), nil
+}
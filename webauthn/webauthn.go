@@ -0,0 +1,415 @@
+// Package webauthn implements the relying party side of WebAuthn
+// registration and authentication ceremonies for passkeys. Only the "none"
+// attestation format is accepted: the service wants proof of possession, not
+// the make of the authenticator.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrVerificationFailed wraps every reason a ceremony response is rejected.
+// ErrSignCountRegressed is one of them, worth reporting on its own: a
+// signature counter that did not move forward means the credential was
+// copied to another authenticator.
+var (
+	ErrVerificationFailed = errors.New("webauthn verification failed")
+	ErrSignCountRegressed = fmt.Errorf("%w: signature counter did not increase, the authenticator may be cloned", ErrVerificationFailed)
+)
+
+const (
+	challengeLength       = 32
+	maxCredentialIDLength = 1023
+
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	credentialTypePublicKey = "public-key"
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+	flagExtensionData          = 0x80
+)
+
+// Config describes the relying party. RPID is the domain credentials are
+// scoped to and Origins the web origins allowed to run ceremonies for it.
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+	Timeout time.Duration
+}
+
+// URLEncodedBytes is binary data carried as unpadded base64url in JSON, as in
+// the WebAuthn JSON serialization.
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          URLEncodedBytes `json:"id"`
+	Name        string          `json:"name"`
+	DisplayName string          `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type       string          `json:"type"`
+	ID         URLEncodedBytes `json:"id"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions is handed to navigator.credentials.create().
+type CreationOptions struct {
+	Challenge              URLEncodedBytes        `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is handed to navigator.credentials.get().
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CredentialCreation is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.create().
+type CredentialCreation struct {
+	ID       string              `json:"id"`
+	RawID    URLEncodedBytes     `json:"rawId"`
+	Type     string              `json:"type"`
+	Response AttestationResponse `json:"response"`
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+	AttestationObject URLEncodedBytes `json:"attestationObject"`
+	Transports        []string        `json:"transports,omitempty"`
+}
+
+// CredentialAssertion is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.get().
+type CredentialAssertion struct {
+	ID       string            `json:"id"`
+	RawID    URLEncodedBytes   `json:"rawId"`
+	Type     string            `json:"type"`
+	Response AssertionResponse `json:"response"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+	AuthenticatorData URLEncodedBytes `json:"authenticatorData"`
+	Signature         URLEncodedBytes `json:"signature"`
+	UserHandle        URLEncodedBytes `json:"userHandle,omitempty"`
+}
+
+// Credential is what a successful registration yields for storage.
+// PublicKey is the COSE_Key exactly as the authenticator sent it.
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// NewChallenge returns a random ceremony challenge.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// CreationOptions asks for a discoverable credential, so the user can later
+// sign in without typing an identifier first.
+func (c Config) CreationOptions(challenge []byte, user UserEntity, exclude []CredentialDescriptor) CreationOptions {
+	return CreationOptions{
+		Challenge: challenge,
+		RP:        RelyingParty{ID: c.RPID, Name: c.RPName},
+		User:      user,
+		PubKeyCredParams: []CredentialParameter{
+			{Type: credentialTypePublicKey, Alg: AlgEdDSA},
+			{Type: credentialTypePublicKey, Alg: AlgES256},
+			{Type: credentialTypePublicKey, Alg: AlgRS256},
+		},
+		Timeout:            c.Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions leaves allowCredentials empty when allow is nil, which lets
+// the authenticator offer any passkey it holds for the relying party.
+func (c Config) RequestOptions(challenge []byte, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          c.Timeout.Milliseconds(),
+		RPID:             c.RPID,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// ClientChallenge returns the challenge a response was made for, so the
+// caller can find the ceremony it belongs to before verifying it.
+func ClientChallenge(clientDataJSON []byte) ([]byte, error) {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return nil, fmt.Errorf("%w: malformed client data", ErrVerificationFailed)
+	}
+	challenge, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || len(challenge) == 0 {
+		return nil, fmt.Errorf("%w: malformed challenge", ErrVerificationFailed)
+	}
+	return challenge, nil
+}
+
+// VerifyRegistration checks a response to CreationOptions built with
+// challenge and returns the new credential.
+func (c Config) VerifyRegistration(response *CredentialCreation, challenge []byte) (*Credential, error) {
+	if response.Type != credentialTypePublicKey {
+		return nil, fmt.Errorf("%w: unexpected credential type %q", ErrVerificationFailed, response.Type)
+	}
+	if err := c.checkClientData(response.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	item, rest, err := decodeCBOR(response.Response.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: malformed attestation object", ErrVerificationFailed)
+	}
+	attestation, _ := item.(map[interface{}]interface{})
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+
+	if format != "none" || len(statement) != 0 {
+		return nil, fmt.Errorf("%w: unsupported attestation format %q", ErrVerificationFailed, format)
+	}
+
+	authData, err := c.checkAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedCredentialData == 0 {
+		return nil, fmt.Errorf("%w: no attested credential data", ErrVerificationFailed)
+	}
+	if !bytes.Equal(authData.credentialID, response.RawID) {
+		return nil, fmt.Errorf("%w: credential ID mismatch", ErrVerificationFailed)
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		SignCount:  authData.signCount,
+		AAGUID:     authData.aaguid,
+		Transports: response.Response.Transports,
+	}, nil
+}
+
+// VerifyAssertion checks a response to RequestOptions built with challenge,
+// signed by the stored credential, and returns the new signature counter.
+func (c Config) VerifyAssertion(response *CredentialAssertion, challenge, coseKey []byte, storedSignCount uint32) (uint32, error) {
+	if response.Type != credentialTypePublicKey {
+		return 0, fmt.Errorf("%w: unexpected credential type %q", ErrVerificationFailed, response.Type)
+	}
+	if err := c.checkClientData(response.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := c.checkAuthenticatorData(response.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := parsePublicKey(coseKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrVerificationFailed, err)
+	}
+
+	clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), response.Response.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, response.Response.Signature) {
+		return 0, fmt.Errorf("%w: invalid signature", ErrVerificationFailed)
+	}
+
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return 0, ErrSignCountRegressed
+	}
+
+	return authData.signCount, nil
+}
+
+func (c Config) checkClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: malformed client data", ErrVerificationFailed)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: unexpected ceremony %q", ErrVerificationFailed, data.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return fmt.Errorf("%w: challenge mismatch", ErrVerificationFailed)
+	}
+
+	if !slices.Contains(c.Origins, data.Origin) {
+		return fmt.Errorf("%w: origin %q is not allowed", ErrVerificationFailed, data.Origin)
+	}
+	if data.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin requests are not allowed", ErrVerificationFailed)
+	}
+
+	return nil
+}
+
+func (c Config) checkAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	authData, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed authenticator data", ErrVerificationFailed)
+	}
+
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return nil, fmt.Errorf("%w: credential belongs to another relying party", ErrVerificationFailed)
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, fmt.Errorf("%w: user was not present", ErrVerificationFailed)
+	}
+	// Passkey logins skip TOTP, so the passkey alone must prove both
+	// possession and a PIN or biometric.
+	if authData.flags&flagUserVerified == 0 {
+		return nil, fmt.Errorf("%w: user was not verified", ErrVerificationFailed)
+	}
+
+	return authData, nil
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errInvalidCBOR
+	}
+
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if authData.flags&flagAttestedCredentialData != 0 {
+		if len(rest) < 18 {
+			return nil, errInvalidCBOR
+		}
+		authData.aaguid = rest[:16]
+		length := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if length == 0 || length > maxCredentialIDLength || length > len(rest) {
+			return nil, errInvalidCBOR
+		}
+		authData.credentialID = rest[:length]
+		rest = rest[length:]
+
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		authData.publicKey = rest[:len(rest)-len(remaining)]
+		rest = remaining
+	}
+
+	if authData.flags&flagExtensionData != 0 {
+		var err error
+		if _, rest, err = decodeCBOR(rest); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rest) != 0 {
+		return nil, errInvalidCBOR
+	}
+	return authData, nil
+}
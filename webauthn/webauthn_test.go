@@ -0,0 +1,202 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"go-tutuplapak-user/webauthn"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = webauthn.Config{
+	RPID:    "localhost",
+	RPName:  "TutupLapak",
+	Origins: []string{"http://localhost:8080"},
+	Timeout: 5 * time.Minute,
+}
+
+var testUser = webauthn.UserEntity{ID: []byte("1"), Name: "name@name.com", DisplayName: "name@name.com"}
+
+// register runs a registration ceremony and returns the stored credential.
+func register(t *testing.T, authenticator *webauthn.SoftwareAuthenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	response, err := authenticator.Create(testConfig.CreationOptions(challenge, testUser, nil))
+	require.NoError(t, err)
+
+	credential, err := testConfig.VerifyRegistration(response, challenge)
+	require.NoError(t, err)
+	return credential
+}
+
+func TestRegistration(t *testing.T) {
+	authenticator, err := webauthn.NewSoftwareAuthenticator("http://localhost:8080")
+	require.NoError(t, err)
+
+	t.Run("Valid Response", func(t *testing.T) {
+		credential := register(t, authenticator)
+
+		assert.Equal(t, authenticator.CredentialID(), credential.ID)
+		assert.NotEmpty(t, credential.PublicKey)
+		assert.Equal(t, uint32(0), credential.SignCount)
+		assert.Equal(t, []string{"internal"}, credential.Transports)
+	})
+
+	t.Run("Survives The JSON Round Trip", func(t *testing.T) {
+		challenge, _ := webauthn.NewChallenge()
+		response, err := authenticator.Create(testConfig.CreationOptions(challenge, testUser, nil))
+		require.NoError(t, err)
+
+		body, err := json.Marshal(response)
+		require.NoError(t, err)
+		var decoded webauthn.CredentialCreation
+		require.NoError(t, json.Unmarshal(body, &decoded))
+
+		returned, err := webauthn.ClientChallenge(decoded.Response.ClientDataJSON)
+		require.NoError(t, err)
+		assert.Equal(t, challenge, returned)
+
+		_, err = testConfig.VerifyRegistration(&decoded, challenge)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong Challenge", func(t *testing.T) {
+		challenge, _ := webauthn.NewChallenge()
+		other, _ := webauthn.NewChallenge()
+		response, err := authenticator.Create(testConfig.CreationOptions(challenge, testUser, nil))
+		require.NoError(t, err)
+
+		_, err = testConfig.VerifyRegistration(response, other)
+		assert.ErrorIs(t, err, webauthn.ErrVerificationFailed)
+		assert.ErrorContains(t, err, "challenge mismatch")
+	})
+
+	t.Run("Phishing Origin", func(t *testing.T) {
+		phished, err := webauthn.NewSoftwareAuthenticator("https://tutuplapak.example")
+		require.NoError(t, err)
+		challenge, _ := webauthn.NewChallenge()
+		response, err := phished.Create(testConfig.CreationOptions(challenge, testUser, nil))
+		require.NoError(t, err)
+
+		_, err = testConfig.VerifyRegistration(response, challenge)
+		assert.ErrorContains(t, err, `origin "https://tutuplapak.example" is not allowed`)
+	})
+
+	t.Run("Other Relying Party", func(t *testing.T) {
+		challenge, _ := webauthn.NewChallenge()
+		options := testConfig.CreationOptions(challenge, testUser, nil)
+		options.RP.ID = "example.com"
+		response, err := authenticator.Create(options)
+		require.NoError(t, err)
+
+		_, err = testConfig.VerifyRegistration(response, challenge)
+		assert.ErrorContains(t, err, "another relying party")
+	})
+
+	t.Run("Login Response Is Not A Registration", func(t *testing.T) {
+		register(t, authenticator)
+		challenge, _ := webauthn.NewChallenge()
+		assertion, err := authenticator.Get(testConfig.RequestOptions(challenge, nil))
+		require.NoError(t, err)
+
+		response := &webauthn.CredentialCreation{
+			RawID: assertion.RawID,
+			Type:  assertion.Type,
+			Response: webauthn.AttestationResponse{
+				ClientDataJSON:    assertion.Response.ClientDataJSON,
+				AttestationObject: assertion.Response.AuthenticatorData,
+			},
+		}
+		_, err = testConfig.VerifyRegistration(response, challenge)
+		assert.ErrorContains(t, err, `unexpected ceremony "webauthn.get"`)
+	})
+}
+
+func TestAssertion(t *testing.T) {
+	authenticator, err := webauthn.NewSoftwareAuthenticator("http://localhost:8080")
+	require.NoError(t, err)
+	credential := register(t, authenticator)
+
+	login := func(t *testing.T, allow []webauthn.CredentialDescriptor) (*webauthn.CredentialAssertion, []byte) {
+		t.Helper()
+		challenge, err := webauthn.NewChallenge()
+		require.NoError(t, err)
+		assertion, err := authenticator.Get(testConfig.RequestOptions(challenge, allow))
+		require.NoError(t, err)
+		return assertion, challenge
+	}
+
+	t.Run("Valid Response Advances The Counter", func(t *testing.T) {
+		assertion, challenge := login(t, nil)
+
+		signCount, err := testConfig.VerifyAssertion(assertion, challenge, credential.PublicKey, credential.SignCount)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), signCount)
+		assert.Equal(t, []byte("1"), []byte(assertion.Response.UserHandle))
+		credential.SignCount = signCount
+	})
+
+	t.Run("Allow List", func(t *testing.T) {
+		assertion, challenge := login(t, []webauthn.CredentialDescriptor{{Type: "public-key", ID: credential.ID}})
+
+		signCount, err := testConfig.VerifyAssertion(assertion, challenge, credential.PublicKey, credential.SignCount)
+
+		require.NoError(t, err)
+		credential.SignCount = signCount
+	})
+
+	t.Run("Cloned Authenticator", func(t *testing.T) {
+		authenticator.SignCount = 0
+		assertion, challenge := login(t, nil)
+
+		_, err := testConfig.VerifyAssertion(assertion, challenge, credential.PublicKey, credential.SignCount)
+
+		assert.ErrorIs(t, err, webauthn.ErrSignCountRegressed)
+		authenticator.SignCount = credential.SignCount
+	})
+
+	t.Run("Tampered Authenticator Data", func(t *testing.T) {
+		assertion, challenge := login(t, nil)
+		assertion.Response.AuthenticatorData[32] |= 0x80
+
+		_, err := testConfig.VerifyAssertion(assertion, challenge, credential.PublicKey, credential.SignCount)
+
+		assert.ErrorIs(t, err, webauthn.ErrVerificationFailed)
+	})
+
+	t.Run("User Not Verified", func(t *testing.T) {
+		authenticator.SkipUserVerification = true
+		defer func() { authenticator.SkipUserVerification = false }()
+		assertion, challenge := login(t, nil)
+
+		_, err := testConfig.VerifyAssertion(assertion, challenge, credential.PublicKey, credential.SignCount)
+
+		assert.ErrorContains(t, err, "user was not verified")
+	})
+
+	t.Run("Signed By Another Key", func(t *testing.T) {
+		other, err := webauthn.NewSoftwareAuthenticator("http://localhost:8080")
+		require.NoError(t, err)
+		otherCredential := register(t, other)
+		assertion, challenge := login(t, nil)
+
+		_, err = testConfig.VerifyAssertion(assertion, challenge, otherCredential.PublicKey, credential.SignCount)
+
+		assert.ErrorContains(t, err, "invalid signature")
+	})
+
+	t.Run("Replayed Challenge Of Another Ceremony", func(t *testing.T) {
+		assertion, _ := login(t, nil)
+		other, _ := webauthn.NewChallenge()
+
+		_, err := testConfig.VerifyAssertion(assertion, other, credential.PublicKey, credential.SignCount)
+
+		assert.ErrorContains(t, err, "challenge mismatch")
+	})
+}